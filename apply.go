@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/drpaneas/gbgraphics/gbgfx"
+)
+
+type applyArgs struct {
+	Rom    string `arg:"positional,required" help:"Path to the original ROM file"`
+	Patch  string `arg:"required,--patch" help:"IPS or BPS patch" placeholder:"<FILE>"`
+	Output string `arg:"required,--output" help:"patched ROM file" placeholder:"<FILE>"`
+}
+
+// runApply applies an IPS or BPS patch, told apart by its first bytes, to the ROM
+func runApply(cmd *applyArgs) error {
+	rom, err := gbgfx.ReadROM(cmd.Rom)
+	if err != nil {
+		return err
+	}
+
+	patch, err := os.ReadFile(cmd.Patch)
+	if err != nil {
+		return err
+	}
+
+	var patched []byte
+
+	switch {
+	case bytes.HasPrefix(patch, []byte("BPS1")):
+		// The CRC32s of the ROM, the patch and the result are all checked
+		patched, err = gbgfx.ApplyBPS(rom.Data, patch)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("BPS patch applied, the CRC32 of the ROM, the patch and the result match")
+	case bytes.HasPrefix(patch, []byte("PATCH")):
+		patched, err = gbgfx.ApplyIPS(rom.Data, patch)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("IPS patch applied (IPS has no checksums, so make sure it's for this ROM)")
+	default:
+		return fmt.Errorf("%s is neither an IPS nor a BPS patch", cmd.Patch)
+	}
+
+	result := &gbgfx.ROM{Data: patched}
+	if header, err := result.Header(); err == nil {
+		for _, warning := range header.Warnings(len(patched)) {
+			fmt.Println("Warning:", warning)
+		}
+	}
+
+	if err := result.Save(cmd.Output); err != nil {
+		return err
+	}
+
+	fmt.Printf("Patched ROM saved to '%s'\n", cmd.Output)
+
+	return nil
+}
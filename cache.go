@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/drpaneas/gbgraphics/gbgfx"
+)
+
+type cacheArgs struct {
+	Purge bool `arg:"--purge" help:"delete all the cached ROM indexes"`
+}
+
+// runCache lists the cached ROM indexes in dir, or deletes them if asked to
+func runCache(dir string, cmd *cacheArgs) error {
+	if cmd.Purge {
+		purged, err := gbgfx.PurgeCache(dir)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Deleted %d cached ROM index(es), %d KB, from %s\n", len(purged), totalSize(purged)/1024, dir)
+
+		return nil
+	}
+
+	cached, err := gbgfx.CachedIndexes(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, index := range cached {
+		fmt.Printf("%s  %8d KB  %s\n", index.SHA1, index.Size/1024, index.ModTime.Format("2006-01-02 15:04"))
+	}
+
+	fmt.Printf("%d cached ROM index(es), %d KB, in %s\n", len(cached), totalSize(cached)/1024, dir)
+
+	return nil
+}
+
+// totalSize adds up the size of the cached indexes
+func totalSize(cached []gbgfx.CachedIndex) int64 {
+	var total int64
+	for _, index := range cached {
+		total += index.Size
+	}
+
+	return total
+}
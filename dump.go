@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/drpaneas/gbgraphics/gbgfx"
+)
+
+type dumpArgs struct {
+	Rom      string `arg:"positional,required" help:"Path to the ROM file"`
+	Output   string `arg:"--output" help:"output file, a number is added for every page" default:"dump.png" placeholder:"<FILE>"`
+	Offset   string `arg:"--offset" help:"ROM offset to start from (e.g. 0x10000)" default:"0" placeholder:"<OFFSET>"`
+	Length   string `arg:"--length" help:"number of bytes to dump (e.g. 0x800), 0 for up to the end of the ROM" default:"0" placeholder:"<BYTES>"`
+	Bank     *int   `arg:"--bank" help:"dump a whole ROM bank instead of --offset and --length" placeholder:"<N>"`
+	Width    int    `arg:"--width" help:"tiles per row" default:"16" placeholder:"<N>"`
+	Rows     int    `arg:"--rows" help:"rows per page, 0 for a single page" default:"32" placeholder:"<N>"`
+	Palette  string `arg:"--palette" help:"colours of the tiles: grey, greyscale, original, bgb or 4 hex colours lightest first" default:"grey" placeholder:"<NAME>"`
+	Mode     string `arg:"--mode" help:"tile arrangement: 8x8, or 8x16 for two tiles one above the other" default:"8x8" placeholder:"<MODE>"`
+	NoLabels bool   `arg:"--no-labels" help:"don't print the BANK:ADDR of every row"`
+}
+
+// runDump renders the raw tile data of a part of the ROM into paged sheets, like a tile viewer
+func runDump(cmd *dumpArgs) error {
+	rom, err := gbgfx.ReadROM(cmd.Rom)
+	if err != nil {
+		return err
+	}
+
+	start, end, err := dumpRange(cmd, len(rom.Data))
+	if err != nil {
+		return err
+	}
+
+	palette, err := gbgfx.ParsePalette(cmd.Palette)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Mode != "8x8" && cmd.Mode != "8x16" {
+		return fmt.Errorf("unknown mode %q, use 8x8 or 8x16", cmd.Mode)
+	}
+
+	pages, err := gbgfx.Dump(rom.Data[start:end], start, gbgfx.DumpOptions{
+		Columns: cmd.Width,
+		Rows:    cmd.Rows,
+		Palette: palette,
+		Tall:    cmd.Mode == "8x16",
+		Labels:  !cmd.NoLabels,
+	})
+	if err != nil {
+		return err
+	}
+
+	withoutPng := strings.TrimSuffix(cmd.Output, ".png")
+
+	for i, page := range pages {
+		filename := fmt.Sprintf("%s_%d.png", withoutPng, i)
+		if err := gbgfx.SavePNG(filename, page.Image); err != nil {
+			return err
+		}
+
+		fmt.Printf("0x%X-0x%X (%s-%s) saved to '%s'\n", page.Start, page.End-1,
+			gbgfx.BankAddress(page.Start), gbgfx.BankAddress(page.End-1), filename)
+	}
+
+	return nil
+}
+
+// dumpRange returns the part of the ROM to dump, from start up to end (not included)
+func dumpRange(cmd *dumpArgs, romLength int) (int, int, error) {
+	if cmd.Bank != nil {
+		start := *cmd.Bank * gbgfx.ROMBankSize
+		if *cmd.Bank < 0 || start >= romLength {
+			return 0, 0, fmt.Errorf("bank %d is past the end of the ROM (%d banks)", *cmd.Bank, (romLength+gbgfx.ROMBankSize-1)/gbgfx.ROMBankSize)
+		}
+
+		end := start + gbgfx.ROMBankSize
+		if end > romLength {
+			end = romLength
+		}
+
+		return start, end, nil
+	}
+
+	start, err := strconv.ParseInt(cmd.Offset, 0, 64)
+	if err != nil || start < 0 || start >= int64(romLength) {
+		return 0, 0, fmt.Errorf("invalid offset %q for a ROM of 0x%X bytes", cmd.Offset, romLength)
+	}
+
+	length, err := strconv.ParseInt(cmd.Length, 0, 64)
+	if err != nil || length < 0 {
+		return 0, 0, fmt.Errorf("invalid length %q", cmd.Length)
+	}
+
+	end := int64(romLength)
+	if length > 0 && start+length < end {
+		end = start + length
+	}
+
+	return int(start), int(end), nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestDumpRange(t *testing.T) {
+	bank := func(n int) *int { return &n }
+
+	tests := []struct {
+		cmd        dumpArgs
+		start, end int
+		ok         bool
+	}{
+		{dumpArgs{Offset: "0", Length: "0"}, 0, 0x8000, true},
+		{dumpArgs{Offset: "0x4000", Length: "0x800"}, 0x4000, 0x4800, true},
+		{dumpArgs{Offset: "0x7F00", Length: "0x800"}, 0x7F00, 0x8000, true}, // up to the end of the ROM
+		{dumpArgs{Bank: bank(1)}, 0x4000, 0x8000, true},
+		{dumpArgs{Bank: bank(2)}, 0, 0, false},
+		{dumpArgs{Bank: bank(-1)}, 0, 0, false},
+		{dumpArgs{Offset: "0x8000", Length: "0"}, 0, 0, false},
+		{dumpArgs{Offset: "x", Length: "0"}, 0, 0, false},
+		{dumpArgs{Offset: "0", Length: "-1"}, 0, 0, false},
+	}
+
+	for _, test := range tests {
+		start, end, err := dumpRange(&test.cmd, 0x8000)
+		if (err == nil) != test.ok || start != test.start || end != test.end {
+			t.Errorf("%+v: 0x%X-0x%X, %v", test.cmd, start, end, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package gbgfx
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Tile is an 8x8 tile in 2BPP format, the way it's stored in the ROM: every row
+// is two bytes, the first one holding the low bit and the second one the high bit
+// of the colour of each pixel.
+type Tile [TileSize]byte
+
+// EncodeTile takes a 8x8 PNG RGBA images and converts it to 2BPP
+// and returns the original byte array (what the GB rom would contain, and you could see with a hex editor).
+// Every pixel holds its shade (0 = lightest, 3 = darkest), as displayed with the identity palette.
+func EncodeTile(imData image.Image) (Tile, error) {
+	var binCode Tile
+
+	// Make sure it is 8x8
+	if imData.Bounds().Dx() != 8 || imData.Bounds().Dy() != 8 {
+		return binCode, fmt.Errorf("tile is not 8x8, it is %dx%d", imData.Bounds().Dx(), imData.Bounds().Dy())
+	}
+
+	if err := checkColor(imData); err != nil {
+		return binCode, err
+	}
+
+	for y := 0; y < 8; y++ {
+		var binLow, binHigh uint8
+
+		for x := 0; x < 8; x++ {
+			var lowBit, highBit uint8
+
+			pixelColor := imData.At(imData.Bounds().Min.X+x, imData.Bounds().Min.Y+y)
+
+			// type assertion must be checked
+			col, ok := pixelColor.(color.RGBA)
+			if !ok {
+				return binCode, fmt.Errorf("pixel (%d,%d) is not RGBA", x, y)
+			}
+
+			r := col.R
+			g := col.G
+			b := col.B
+
+			if r == g && g == b && (r == 0 || r == 85 || r == 170 || r == 255) {
+				switch r {
+				case 0: // black, r = 3
+					highBit = 1
+					lowBit = 1
+				case 85: // dark gray, r = 2
+					highBit = 1
+					lowBit = 0
+				case 170: // light gray, r = 1
+					highBit = 0
+					lowBit = 1
+				case 255: // white, r = 0
+					highBit = 0
+					lowBit = 0
+				}
+			} else {
+				// Find the closest palette colour
+				if red, green, blue := GetPaletteColour(darkest, PaletteBGB); r == red && g == green && b == blue {
+					highBit = 1
+					lowBit = 1
+				} else if red, green, blue := GetPaletteColour(dark, PaletteBGB); r == red && g == green && b == blue {
+					highBit = 1
+					lowBit = 0
+				} else if red, green, blue := GetPaletteColour(light, PaletteBGB); r == red && g == green && b == blue {
+					highBit = 0
+					lowBit = 1
+				} else if red, green, blue := GetPaletteColour(lightest, PaletteBGB); r == red && g == green && b == blue {
+					highBit = 0
+					lowBit = 0
+				} else {
+					return binCode, fmt.Errorf("unknown colour #%02X%02X%02X at pixel (%d,%d)", r, g, b, x, y)
+				}
+			}
+
+			binLow += lowBit * uint8(math.Pow(2, float64(7-x)))
+			binHigh += highBit * uint8(math.Pow(2, float64(7-x)))
+		}
+
+		binCode[2*y], binCode[2*y+1] = binLow, binHigh
+	}
+
+	return binCode, nil
+}
+
+// TileImage renders tiles in 2BPP format as a greyscale image, 8 pixels wide.
+// A trailing partial tile is drawn as far as its bytes go, over a white background.
+func TileImage(data []byte) *image.RGBA {
+	// Calculate the height of the img in bytes
+	height := 8 * int(math.Ceil(float64(len(data))/float64(8*8*bitDepth)))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.White)
+		}
+	}
+
+	xPos, yPos := 0, 0
+
+	// Modify the img
+	convert2BPPToPNG(height, data, img, xPos, yPos)
+
+	return img
+}
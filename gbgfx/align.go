@@ -0,0 +1,186 @@
+package gbgfx
+
+import (
+	"fmt"
+	"image"
+	"sort"
+)
+
+// Alignment is the position, in screenshot pixels, where the first whole
+// background tile starts. It is the sub-tile phase caused by the SCX/SCY
+// scroll registers: X == (8 - SCX%8) % 8 and Y == (8 - SCY%8) % 8.
+type Alignment struct {
+	X int
+	Y int
+}
+
+func (a Alignment) String() string {
+	return fmt.Sprintf("x=%d,y=%d (SCX%%8=%d, SCY%%8=%d)", a.X, a.Y, (8-a.X)%8, (8-a.Y)%8)
+}
+
+// AllAlignments returns the 64 possible (x,y) sub-tile phases of the background grid
+func AllAlignments() []Alignment {
+	var alignments []Alignment
+
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			alignments = append(alignments, Alignment{X: x, Y: y})
+		}
+	}
+
+	return alignments
+}
+
+// cropImage returns a copy of src without its first align.X columns and align.Y rows
+func cropImage(src image.Image, align Alignment) image.Image {
+	bounds := src.Bounds()
+	cropped := image.NewRGBA(image.Rect(0, 0, bounds.Dx()-align.X, bounds.Dy()-align.Y))
+
+	for y := bounds.Min.Y + align.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X + align.X; x < bounds.Max.X; x++ {
+			cropped.Set(x-bounds.Min.X-align.X, y-bounds.Min.Y-align.Y, src.At(x, y))
+		}
+	}
+
+	return cropped
+}
+
+// AlignmentScore is a candidate alignment together with how likely it is
+// to be the real phase of the background grid (0 = unlikely, 1 = certain)
+type AlignmentScore struct {
+	Align Alignment
+	Score float64
+}
+
+// RankAlignments scores all 64 alignments of the screenshot without a ROM and
+// returns them from the most to the least likely one. It's only a guess, which
+// can prefer an alignment that drops a row or a column; ROM.RankAlignments checks
+// the tiles against the ROM instead.
+// Backgrounds are built from a small tileset, so on the correct alignment the
+// same 8x8 tiles show up again and again, while on a wrong alignment every tile
+// is cut in half and the number of distinct tiles grows.
+// The score is the fraction of tiles that repeat an earlier tile.
+func (s *Screenshot) RankAlignments() []AlignmentScore {
+	var ranked []AlignmentScore
+
+	for _, align := range AllAlignments() {
+		tiles := s.Tiles(align)
+		if len(tiles) == 0 {
+			continue
+		}
+
+		seen := make(map[string]bool)
+		for _, tile := range tiles {
+			seen[string(tile.(*image.RGBA).Pix)] = true
+		}
+
+		ranked = append(ranked, AlignmentScore{
+			Align: align,
+			Score: 1 - float64(len(seen))/float64(len(tiles)),
+		})
+	}
+
+	// Keep the natural order for equal scores, so x=0,y=0 wins a tie
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+
+	return ranked
+}
+
+// RankAlignments scores all 64 alignments of the screenshot against the ROM and
+// returns them from the most to the least likely one.
+// On the correct alignment the tiles of the screenshot are the tiles of the ROM,
+// while on a wrong one every tile is cut in half and isn't found, except for the
+// blank ones. The score is the fraction of the unique tiles that are found in the
+// ROM, so it doesn't depend on how many whole tiles the alignment leaves.
+// Equal scores (e.g. nothing found) are ranked by Screenshot.RankAlignments.
+func (r *ROM) RankAlignments(s *Screenshot, opts SearchOptions) ([]AlignmentScore, error) {
+	repeats := make(map[Alignment]float64)
+	for _, candidate := range s.RankAlignments() {
+		repeats[candidate.Align] = candidate.Score
+	}
+
+	opts.AllOccurrences = false
+
+	var ranked []AlignmentScore
+
+	for _, align := range AllAlignments() {
+		tiles, err := s.uniqueCodes(align)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(tiles) == 0 {
+			continue
+		}
+
+		found := make(map[Tile]bool)
+		for _, match := range r.FindTiles(tiles, opts) {
+			found[match.ScreenTile] = true
+		}
+
+		ranked = append(ranked, AlignmentScore{
+			Align: align,
+			Score: float64(len(found)) / float64(len(tiles)),
+		})
+	}
+
+	// Keep the natural order for equal scores, so x=0,y=0 wins a tie
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+
+		return repeats[ranked[i].Align] > repeats[ranked[j].Align]
+	})
+
+	return ranked, nil
+}
+
+// uniqueCodes is UniqueTiles for ranking: the tiles are encoded first and the
+// 2BPP codes compared, which is much faster than comparing the images 64 times.
+// In CGB mode the tiles with too many colours are skipped.
+func (s *Screenshot) uniqueCodes(align Alignment) ([]Tile, error) {
+	var codes []Tile
+	seen := make(map[Tile]bool)
+
+	for _, tile := range s.Tiles(align) {
+		code, err := s.encode(tile)
+		if err != nil {
+			if s.CGB {
+				continue
+			}
+
+			return nil, err
+		}
+
+		if seen[code] {
+			continue
+		}
+
+		// Flipped copies are the same tile, the search tries every flip
+		for _, f := range Flips {
+			seen[code.Flip(f)] = true
+		}
+
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// ParseAlignment parses an alignment given as "X,Y" (e.g. "3,5")
+func ParseAlignment(v string) (Alignment, error) {
+	var align Alignment
+
+	if _, err := fmt.Sscanf(v, "%d,%d", &align.X, &align.Y); err != nil {
+		return Alignment{}, fmt.Errorf("invalid alignment %q, expected X,Y (e.g. 3,5): %w", v, err)
+	}
+
+	if align.X < 0 || align.X > 7 || align.Y < 0 || align.Y > 7 {
+		return Alignment{}, fmt.Errorf("invalid alignment %q, X and Y must be between 0 and 7", v)
+	}
+
+	return align, nil
+}
@@ -0,0 +1,79 @@
+package gbgfx
+
+import (
+	"image"
+	"image/draw"
+	"math/rand"
+	"testing"
+)
+
+// romScreen returns a ROM holding a few tiles, at the multiples of 16 from 0x4000,
+// and a screen built out of them whose background grid starts at align
+func romScreen(rnd *rand.Rand, align Alignment) (*ROM, *image.RGBA) {
+	rom := &ROM{Data: make([]byte, 0x8000)}
+
+	var tiles []*image.RGBA
+	for i := 0; i < 24; i++ {
+		tile := randomTile(rnd)
+		copy(rom.Data[0x4000+i*TileSize:], tile[:])
+		tiles = append(tiles, TileImage(tile[:]))
+	}
+
+	screen := image.NewRGBA(image.Rect(0, 0, gbScreenXRes, gbScreenYRes))
+	for y := align.Y - 8; y < gbScreenYRes; y += 8 {
+		for x := align.X - 8; x < gbScreenXRes; x += 8 {
+			draw.Draw(screen, image.Rect(x, y, x+8, y+8), tiles[rnd.Intn(len(tiles))], image.Point{}, draw.Src)
+		}
+	}
+
+	return rom, screen
+}
+
+func TestRankAlignments(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for _, align := range []Alignment{{0, 0}, {3, 5}, {7, 1}} {
+		rom, screen := romScreen(rnd, align)
+
+		s, err := NewScreenshot(screen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Without the ROM, the tiles repeat the most on the real grid
+		if ranked := s.RankAlignments(); len(ranked) != 64 || ranked[0].Align != align {
+			t.Errorf("%v: %d alignments, the first one is %v", align, len(ranked), ranked[0].Align)
+		}
+
+		ranked, err := rom.RankAlignments(s, SearchOptions{BGPs: []byte{IdentityBGP}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ranked[0].Align != align || ranked[0].Score != 1 || ranked[1].Score >= 1 {
+			t.Errorf("%v: the first alignments are %+v and %+v", align, ranked[0], ranked[1])
+		}
+
+		// Without the tiles in the ROM, the guess without it breaks the tie
+		ranked, err = (&ROM{Data: make([]byte, 0x8000)}).RankAlignments(s, SearchOptions{BGPs: []byte{IdentityBGP}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ranked[0].Align != align || ranked[0].Score != 0 {
+			t.Errorf("%v: nothing found, the first alignment is %+v", align, ranked[0])
+		}
+	}
+}
+
+func TestParseAlignment(t *testing.T) {
+	if got, err := ParseAlignment("3,5"); err != nil || got != (Alignment{X: 3, Y: 5}) {
+		t.Errorf("3,5: %v, %v", got, err)
+	}
+
+	for _, v := range []string{"", "3", "8,0", "0,-1", "a,b"} {
+		if _, err := ParseAlignment(v); err == nil {
+			t.Errorf("%q: no error", v)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package gbgfx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// BGB saves its states (.sna) as a list of blocks, each one a name ending with a
+// zero byte, the size of its data (4 bytes, little endian) and the data. The blocks
+// read here are the memory BGB shows in its debugger: the VRAM (both banks on the
+// CGB), the OAM, the I/O registers ($FF00-$FF7F) and the CGB palette RAM.
+const (
+	bgbVRAM    = "VRAM"
+	bgbOAM     = "OAM"
+	bgbIO      = "IO"
+	bgbBGPals  = "BGPAL"
+	bgbOBJPals = "OBJPAL"
+	bgbMaxName = 32
+	bgbIOSize  = 0x80
+)
+
+// bgbBlocks splits a BGB save state into its blocks. It returns false if the data
+// isn't made of blocks up to its end, so other files aren't taken for one.
+func bgbBlocks(data []byte) (map[string][]byte, bool) {
+	blocks := make(map[string][]byte)
+
+	for p := 0; p < len(data); {
+		start := p
+		for p < len(data) && p-start <= bgbMaxName && data[p] >= 0x20 && data[p] < 0x7F {
+			p++
+		}
+
+		if p == start || p-start > bgbMaxName || p+5 > len(data) || data[p] != 0 {
+			return nil, false
+		}
+
+		name := string(data[start:p])
+		size := int(binary.LittleEndian.Uint32(data[p+1:]))
+		p += 5
+
+		if size < 0 || size > len(data)-p {
+			return nil, false
+		}
+
+		blocks[name] = data[p : p+size]
+		p += size
+	}
+
+	return blocks, len(blocks) > 0
+}
+
+// parseBGB reads the video registers and the memory of the blocks of a BGB save
+// state. The model isn't saved with them, a VRAM of 2 banks makes it a CGB.
+func parseBGB(blocks map[string][]byte) (*SaveState, error) {
+	vram, ok := blocks[bgbVRAM]
+	if !ok {
+		return nil, errors.New("BGB save state without a VRAM block")
+	}
+
+	if len(vram) != VRAMBankSize && len(vram) != 2*VRAMBankSize {
+		return nil, fmt.Errorf("BGB VRAM is %d bytes, expected 8 KB or 16 KB", len(vram))
+	}
+
+	regs := blocks[bgbIO]
+	if len(regs) < bgbIOSize {
+		return nil, fmt.Errorf("BGB I/O registers are %d bytes, expected %d", len(regs), bgbIOSize)
+	}
+
+	oam := blocks[bgbOAM]
+	if len(oam) > oamEntries*oamEntrySize {
+		oam = oam[:oamEntries*oamEntrySize]
+	}
+
+	s := &SaveState{
+		Model:       "GD  ",
+		OAM:         oam,
+		BGPalettes:  blocks[bgbBGPals],
+		OBJPalettes: blocks[bgbOBJPals],
+		LCDC:        regs[regLCDC],
+		SCX:         regs[regSCX],
+		SCY:         regs[regSCY],
+		WX:          regs[regWX],
+		WY:          regs[regWY],
+		BGP:         regs[regBGP],
+		OBP0:        regs[regOBP0],
+		OBP1:        regs[regOBP1],
+	}
+
+	if len(vram) == 2*VRAMBankSize {
+		s.Model = "CC  "
+	}
+
+	var err error
+	if s.VRAM, err = ParseVRAMDump(vram); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
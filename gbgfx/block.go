@@ -0,0 +1,196 @@
+package gbgfx
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// Block is a run of tile data in the ROM, from Start up to End (not included).
+// Games store their graphics as blocks that are copied to VRAM together, so the
+// block around a tile of the screenshot also holds the tiles and the animation
+// frames that weren't on the screen.
+type Block struct {
+	Start int
+	End   int
+	Hits  int // tiles of the screenshot in the block
+}
+
+// Tiles returns the number of tiles in the block
+func (b Block) Tiles() int {
+	return (b.End - b.Start) / TileSize
+}
+
+// Offsets returns the offset of every tile of the block
+func (b Block) Offsets() []int {
+	var offsets []int
+	for offset := b.Start; offset+TileSize <= b.End; offset += TileSize {
+		offsets = append(offsets, offset)
+	}
+
+	return offsets
+}
+
+// BlockOptions limit how far a match is expanded
+type BlockOptions struct {
+	MaxTiles int // tiles to walk backward and forward from every match, 0 means up to the end of the bank
+}
+
+// ExpandMatches expands every match into the block of tiles around it, walking
+// backward and forward a tile at a time until the ROM bank ends, the data turns
+// into 0xFF padding or into code and other data (see endsBlock), or opts.MaxTiles
+// is reached. Blocks of the same tile grid (the same offset modulo 16) that overlap
+// are merged, and the blocks are returned in ROM order.
+// The tiles of a game share a grid, so a match off the grid of most matches is
+// usually a coincidence: its block is left out, unless other matches are in it too.
+func (r *ROM) ExpandMatches(matches []Match, opts BlockOptions) []Block {
+	var blocks []Block
+	var hitsPerPhase [TileSize]int
+
+	for _, match := range matches {
+		blocks = append(blocks, r.expandBlock(match.Offset, opts.MaxTiles))
+		hitsPerPhase[match.Offset%TileSize]++
+	}
+
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Start < blocks[j].Start })
+
+	// The last merged block of every grid, blocks of other grids may be in between
+	var merged []Block
+	last := make(map[int]int)
+
+	for _, block := range blocks {
+		phase := block.Start % TileSize
+
+		if i, ok := last[phase]; ok && block.Start <= merged[i].End {
+			if block.End > merged[i].End {
+				merged[i].End = block.End
+			}
+
+			merged[i].Hits += block.Hits
+
+			continue
+		}
+
+		last[phase] = len(merged)
+		merged = append(merged, block)
+	}
+
+	// The grid of most matches, the aligned one in a tie
+	majority := 0
+	for phase, hits := range hitsPerPhase {
+		if hits > hitsPerPhase[majority] {
+			majority = phase
+		}
+	}
+
+	var kept []Block
+	for _, block := range merged {
+		if block.Hits > 1 || block.Start%TileSize == majority {
+			kept = append(kept, block)
+		}
+	}
+
+	return kept
+}
+
+// expandBlock walks from the tile at offset as far as the tile data goes
+func (r *ROM) expandBlock(offset, maxTiles int) Block {
+	bank := offset / ROMBankSize
+	block := Block{Start: offset, End: offset + TileSize, Hits: 1}
+
+	for steps := 0; maxTiles == 0 || steps < maxTiles; steps++ {
+		previous := block.Start - TileSize
+		if r.endsBlock(previous, -TileSize, bank) {
+			break
+		}
+
+		block.Start = previous
+	}
+
+	for steps := 0; maxTiles == 0 || steps < maxTiles; steps++ {
+		if r.endsBlock(block.End, TileSize, bank) {
+			break
+		}
+
+		block.End += TileSize
+	}
+
+	return block
+}
+
+const (
+	// noiseRun is how many tiles in a row must look like noise to end a block,
+	// a few tiles of a detailed picture can look like it too
+	noiseRun = 4
+	// noiseChanges is how many of the 112 pairs of neighbouring pixels of a tile
+	// must differ, both across and down, for the tile to look like noise
+	noiseChanges = 36
+)
+
+// endsBlock tells if the tile at offset, walking by step, is past the end of the
+// tile data: out of the bank, 0xFF padding, or code and other data.
+// A single tile of 0xFF is a solid tile of the darkest colour, so padding has to
+// run for more than one tile. Code and data look like noise once they are drawn
+// as tiles, while graphics have shapes: neighbouring pixels are mostly alike,
+// across or down. So the data ends where noiseRun tiles in a row look like noise.
+func (r *ROM) endsBlock(offset, step, bank int) bool {
+	tile, ok := r.bankTile(offset, bank)
+	if !ok {
+		return true
+	}
+
+	if next, ok := r.bankTile(offset+step, bank); isPadding(tile) && ok && isPadding(next) {
+		return true
+	}
+
+	for i := 0; i < noiseRun; i++ {
+		if !looksLikeNoise(tile) {
+			return false
+		}
+
+		if tile, ok = r.bankTile(offset+(i+1)*step, bank); !ok {
+			break
+		}
+	}
+
+	return true
+}
+
+// bankTile returns the tile at offset, if it is whole and in the bank
+func (r *ROM) bankTile(offset, bank int) ([]byte, bool) {
+	if offset < 0 || offset+TileSize > len(r.Data) || offset/ROMBankSize != bank || (offset+TileSize-1)/ROMBankSize != bank {
+		return nil, false
+	}
+
+	return r.Data[offset : offset+TileSize], true
+}
+
+// isPadding tells if the tile is the 0xFF filler of the unused ROM space
+func isPadding(tile []byte) bool {
+	for _, b := range tile {
+		if b != 0xFF {
+			return false
+		}
+	}
+
+	return true
+}
+
+// looksLikeNoise tells if the pixels of the tile change about as often as random
+// bytes would make them: across the rows, down the columns, and between the two
+// bit planes (about half of the bits of the planes differ in noise, while graphics
+// often use the same bits in both, or opposite ones)
+func looksLikeNoise(tile []byte) bool {
+	var across, down, planes int
+
+	for row := 0; row < TileSize; row += 2 {
+		low, high := tile[row], tile[row+1]
+		across += bits.OnesCount8((low^low>>1)&0x7F) + bits.OnesCount8((high^high>>1)&0x7F)
+		planes += bits.OnesCount8(low ^ high)
+
+		if row+2 < TileSize {
+			down += bits.OnesCount8(low^tile[row+2]) + bits.OnesCount8(high^tile[row+3])
+		}
+	}
+
+	return across >= noiseChanges && down >= noiseChanges && planes >= 16 && planes <= 48
+}
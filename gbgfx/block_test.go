@@ -0,0 +1,100 @@
+package gbgfx
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// fill sets the bytes of the ROM from start to end
+func fill(rom []byte, start, end int, b byte) {
+	for i := start; i < end; i++ {
+		rom[i] = b
+	}
+}
+
+func TestExpandBlock(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	// Blank tiles in bank 1, which aren't noise, with a solid darkest tile in them,
+	// two tiles of 0xFF padding before them and random bytes after them
+	data := make([]byte, 4*ROMBankSize)
+	fill(data, 0x4100, 0x4120, 0xFF)
+	fill(data, 0x4300, 0x4310, 0xFF)
+	rnd.Read(data[0x5000:0x6000])
+	rom := &ROM{Data: data}
+
+	tests := []struct {
+		name       string
+		offset     int
+		maxTiles   int
+		start, end int
+	}{
+		{"padding and noise", 0x4400, 0, 0x4120, 0x5000},
+		{"limit", 0x4400, 2, 0x43E0, 0x4430},
+		{"bank start", 0x4010, 0, 0x4000, 0x4100},
+		{"off the grid", 0x4008, 0, 0x4008, 0x5008}, // the padding is cut in halves, which aren't 0xFF tiles
+		{"bank end", 0xC000, 0, 0xC000, 0x10000},
+	}
+
+	for _, test := range tests {
+		block := rom.expandBlock(test.offset, test.maxTiles)
+		if block.Start != test.start || block.End != test.end {
+			t.Errorf("%s: block 0x%X-0x%X, want 0x%X-0x%X", test.name, block.Start, block.End, test.start, test.end)
+		}
+	}
+}
+
+func TestExpandMatches(t *testing.T) {
+	rom := &ROM{Data: make([]byte, 2*ROMBankSize)}
+	matchesAt := func(offsets ...int) []Match {
+		var matches []Match
+		for _, offset := range offsets {
+			matches = append(matches, Match{Offset: offset})
+		}
+
+		return matches
+	}
+
+	tests := []struct {
+		name    string
+		matches []Match
+		want    []Block
+	}{
+		{
+			// The block of the other grid sorts between the two blocks of the aligned one
+			"grids", matchesAt(0x5000, 0x5018, 0x5020, 0x5038),
+			[]Block{{Start: 0x4FE0, End: 0x5050, Hits: 2}, {Start: 0x4FF8, End: 0x5068, Hits: 2}},
+		},
+		{
+			"coincidence", matchesAt(0x5000, 0x5020, 0x6008),
+			[]Block{{Start: 0x4FE0, End: 0x5050, Hits: 2}},
+		},
+		{
+			"apart", matchesAt(0x5000, 0x6000),
+			[]Block{{Start: 0x4FE0, End: 0x5030, Hits: 1}, {Start: 0x5FE0, End: 0x6030, Hits: 1}},
+		},
+	}
+
+	for _, test := range tests {
+		blocks := rom.ExpandMatches(test.matches, BlockOptions{MaxTiles: 2})
+		if len(blocks) != len(test.want) {
+			t.Errorf("%s: blocks %+v, want %+v", test.name, blocks, test.want)
+			continue
+		}
+
+		seen := make(map[int]bool)
+		for i, block := range blocks {
+			if block != test.want[i] {
+				t.Errorf("%s: block %d is %+v, want %+v", test.name, i, block, test.want[i])
+			}
+
+			for _, offset := range block.Offsets() {
+				if seen[offset] {
+					t.Errorf("%s: tile 0x%X is in two blocks", test.name, offset)
+				}
+
+				seen[offset] = true
+			}
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package gbgfx
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// indexMagic identifies the cached index files, followed by their format version
+var indexMagic = []byte("GBGI\x01")
+
+// indexExt is the extension of the cached index files, named after the SHA-1 of their ROM
+const indexExt = ".idx"
+
+// CacheDir returns dir, or the gbgraphics directory inside the user's cache directory if dir is empty
+func CacheDir(dir string) (string, error) {
+	if dir != "" {
+		return dir, nil
+	}
+
+	userDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the cache directory: %w", err)
+	}
+
+	return filepath.Join(userDir, "gbgraphics"), nil
+}
+
+// LoadIndex returns the index of the ROM from the cache in dir, or builds it and
+// stores it there for the next run. With an empty dir the cache isn't used at all.
+// A cached index that can't be read is simply built again.
+// The cache is only a shortcut, so the returned index is always usable,
+// and the error only tells that the index couldn't be stored.
+func LoadIndex(dir string, rom []byte) (*Index, error) {
+	if dir == "" {
+		return NewIndex(rom), nil
+	}
+
+	sum := sha1.Sum(rom)
+	path := filepath.Join(dir, hex.EncodeToString(sum[:])+indexExt)
+
+	if index, err := readIndex(path, rom, sum); err == nil {
+		return index, nil
+	}
+
+	index := NewIndex(rom)
+
+	if err := writeIndex(path, index, sum); err != nil {
+		return index, fmt.Errorf("failed to cache the ROM index: %w", err)
+	}
+
+	return index, nil
+}
+
+// readIndex reads a cached index and makes sure it belongs to the ROM
+func readIndex(path string, rom []byte, sum [sha1.Size]byte) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	header := make([]byte, len(indexMagic)+sha1.Size)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if !bytes.Equal(header[:len(indexMagic)], indexMagic) || !bytes.Equal(header[len(indexMagic):], sum[:]) {
+		return nil, fmt.Errorf("%s: not an index of this ROM", path)
+	}
+
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if len(rom) < rangeLength || int(count) != len(rom)-rangeLength+1 {
+		return nil, fmt.Errorf("%s: wrong number of entries", path)
+	}
+
+	entries := make([]uint64, count)
+	if err := binary.Read(r, binary.LittleEndian, entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &Index{rom: rom, entries: entries}, nil
+}
+
+// writeIndex stores the index in path. It's written to a temporary file first,
+// so a run that gets interrupted never leaves a half-written index behind.
+func writeIndex(path string, index *Index, sum [sha1.Size]byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "index-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	w := bufio.NewWriter(f)
+	w.Write(indexMagic)
+	w.Write(sum[:])
+	binary.Write(w, binary.LittleEndian, uint32(len(index.entries)))
+	binary.Write(w, binary.LittleEndian, index.entries)
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// CachedIndex describes an index file in the cache
+type CachedIndex struct {
+	Path    string
+	SHA1    string // SHA-1 of the ROM, in hex
+	Size    int64
+	ModTime time.Time
+}
+
+// CachedIndexes lists the index files in the cache directory.
+// A cache directory that doesn't exist yet is just empty.
+func CachedIndexes(dir string) ([]CachedIndex, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var cached []CachedIndex
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), indexExt) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		cached = append(cached, CachedIndex{
+			Path:    filepath.Join(dir, entry.Name()),
+			SHA1:    strings.TrimSuffix(entry.Name(), indexExt),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+
+	return cached, nil
+}
+
+// PurgeCache deletes the index files in the cache directory and returns them
+func PurgeCache(dir string) ([]CachedIndex, error) {
+	cached, err := CachedIndexes(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, index := range cached {
+		if err := os.Remove(index.Path); err != nil {
+			return cached[:i], err
+		}
+	}
+
+	return cached, nil
+}
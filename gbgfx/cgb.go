@@ -0,0 +1,185 @@
+package gbgfx
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strings"
+)
+
+// colorsPerTile is how many colours a tile can have: one per value of its 2 bits
+const colorsPerTile = 4
+
+// CGBPalette is a background palette of the Gameboy Color, inferred from a screenshot.
+// The colours are in the order of the colour numbers of the tiles, and only the
+// ones the tiles of the screenshot use are known.
+type CGBPalette struct {
+	Colours [colorsPerTile]color.RGBA
+	Known   [colorsPerTile]bool
+}
+
+// String prints the colours as #RRGGBB, and the unknown ones as -------
+func (p CGBPalette) String() string {
+	var colours []string
+	for i, c := range p.Colours {
+		if !p.Known[i] {
+			colours = append(colours, "-------")
+			continue
+		}
+
+		colours = append(colours, fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B))
+	}
+
+	return strings.Join(colours, " ")
+}
+
+// merge combines two palettes, if they have the same colour wherever both are known
+func (p CGBPalette) merge(other CGBPalette) (CGBPalette, bool) {
+	for i := range p.Colours {
+		if p.Known[i] && other.Known[i] && p.Colours[i] != other.Colours[i] {
+			return p, false
+		}
+	}
+
+	for i := range p.Colours {
+		if !p.Known[i] && other.Known[i] {
+			p.Colours[i], p.Known[i] = other.Colours[i], true
+		}
+	}
+
+	return p, true
+}
+
+// luminance of a colour, with the weights of ITU-R BT.601
+func luminance(c color.RGBA) int {
+	return 299*int(c.R) + 587*int(c.G) + 114*int(c.B)
+}
+
+// rgb packs the colour in a number
+func rgb(c color.RGBA) uint32 {
+	return uint32(c.R)<<16 | uint32(c.G)<<8 | uint32(c.B)
+}
+
+// tileColours returns the different colours of the tile, lightest first
+func tileColours(tile image.Image) []color.RGBA {
+	seen := make(map[color.RGBA]bool)
+
+	var colours []color.RGBA
+
+	for y := tile.Bounds().Min.Y; y < tile.Bounds().Max.Y; y++ {
+		for x := tile.Bounds().Min.X; x < tile.Bounds().Max.X; x++ {
+			c := color.RGBAModel.Convert(tile.At(x, y)).(color.RGBA)
+			if !seen[c] {
+				seen[c] = true
+				colours = append(colours, c)
+			}
+		}
+	}
+
+	sort.Slice(colours, func(i, j int) bool {
+		if li, lj := luminance(colours[i]), luminance(colours[j]); li != lj {
+			return li > lj
+		}
+
+		// Same brightness, any order will do as long as it's always the same
+		return rgb(colours[i]) < rgb(colours[j])
+	})
+
+	return colours
+}
+
+// EncodeCGBTile converts an 8x8 tile of a Gameboy Color screenshot to 2BPP.
+// The colours are ranked by luminance, so the lightest is shade 0 and the darkest
+// shade 3, which is the usual order of CGB palettes. The search tries the other
+// orders anyway, the same as it tries every BGP of a DMG screenshot.
+// It returns the colours of every shade too.
+func EncodeCGBTile(tile image.Image) (Tile, []color.RGBA, error) {
+	var code Tile
+
+	if tile.Bounds().Dx() != 8 || tile.Bounds().Dy() != 8 {
+		return code, nil, fmt.Errorf("tile is not 8x8, it is %dx%d", tile.Bounds().Dx(), tile.Bounds().Dy())
+	}
+
+	colours := tileColours(tile)
+	if len(colours) > colorsPerTile {
+		return code, nil, fmt.Errorf("tile has %d colours, a tile can't have more than %d", len(colours), colorsPerTile)
+	}
+
+	shadeOf := make(map[color.RGBA]byte)
+	for shade, c := range colours {
+		shadeOf[c] = byte(shade)
+	}
+
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			c := color.RGBAModel.Convert(tile.At(tile.Bounds().Min.X+x, tile.Bounds().Min.Y+y)).(color.RGBA)
+			shade := shadeOf[c]
+
+			code[2*y] |= (shade & 1) << (7 - x)
+			code[2*y+1] |= (shade >> 1) << (7 - x)
+		}
+	}
+
+	return code, colours, nil
+}
+
+// withFewColours drops the tiles with more than 4 colours, e.g. where a sprite
+// is over the background, because they can't be in the ROM as they are
+func withFewColours(tiles []image.Image) []image.Image {
+	var kept []image.Image
+
+	for _, tile := range tiles {
+		if len(tileColours(tile)) <= colorsPerTile {
+			kept = append(kept, tile)
+		}
+	}
+
+	return kept
+}
+
+// Palettes infers the background palettes of a Gameboy Color screenshot from
+// its tilemap: the BGP of every match tells which shade, so which colour of the
+// tile, every colour number of the ROM tile has. Palettes that agree on all the
+// colours they both know are merged. It also returns the palette number of
+// every entry of the tilemap, -1 if its tile wasn't found.
+func (s *Screenshot) Palettes(tilemap *Tilemap) ([]CGBPalette, []int, error) {
+	tiles := s.Tiles(tilemap.Align)
+	if len(tiles) != len(tilemap.Entries) {
+		return nil, nil, fmt.Errorf("tilemap has %d entries, the screenshot %d tiles", len(tilemap.Entries), len(tiles))
+	}
+
+	var palettes []CGBPalette
+	numbers := make([]int, len(tiles))
+
+	for j, entry := range tilemap.Entries {
+		numbers[j] = -1
+
+		if !entry.Found {
+			continue
+		}
+
+		colours := tileColours(tiles[j])
+
+		var palette CGBPalette
+		for i := range palette.Colours {
+			if shade := int(entry.Match.BGP>>(2*i)) & 3; shade < len(colours) {
+				palette.Colours[i], palette.Known[i] = colours[shade], true
+			}
+		}
+
+		for n := range palettes {
+			if merged, ok := palettes[n].merge(palette); ok {
+				palettes[n], numbers[j] = merged, n
+				break
+			}
+		}
+
+		if numbers[j] == -1 {
+			palettes = append(palettes, palette)
+			numbers[j] = len(palettes) - 1
+		}
+	}
+
+	return palettes, numbers, nil
+}
@@ -0,0 +1,127 @@
+package gbgfx
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+// cgbColours are two palettes of 4 colours, not ordered by brightness
+var cgbColours = [2][colorsPerTile]color.RGBA{
+	{{R: 0x20, G: 0x40, B: 0xF8, A: 0xFF}, {R: 0xF8, G: 0xF8, B: 0x80, A: 0xFF}, {R: 0x08, G: 0x08, B: 0x08, A: 0xFF}, {R: 0xC0, G: 0x20, B: 0x20, A: 0xFF}},
+	{{R: 0xF8, G: 0xF8, B: 0xF8, A: 0xFF}, {R: 0x00, G: 0x80, B: 0x00, A: 0xFF}, {R: 0x80, G: 0xF8, B: 0x80, A: 0xFF}, {R: 0x00, G: 0x20, B: 0x40, A: 0xFF}},
+}
+
+// drawCGBTile draws the tile in the colours of the palette, with its top left pixel at x,y
+func drawCGBTile(img *image.RGBA, x, y int, tile Tile, palette [colorsPerTile]color.RGBA) {
+	for py := 0; py < 8; py++ {
+		for px := 0; px < 8; px++ {
+			img.SetRGBA(x+px, y+py, palette[pixel(tile, px, py)])
+		}
+	}
+}
+
+func TestEncodeCGBTile(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	tile := randomTile(rnd)
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	drawCGBTile(img, 0, 0, tile, cgbColours[0])
+
+	code, colours, err := EncodeCGBTile(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The colours ranked from the lightest are 1, 3, 0 and 2
+	want := []color.RGBA{cgbColours[0][1], cgbColours[0][3], cgbColours[0][0], cgbColours[0][2]}
+	for i := range want {
+		if colours[i] != want[i] {
+			t.Errorf("colour %d is %v, want %v", i, colours[i], want[i])
+		}
+	}
+
+	// So the shades are the colours shown with BGP $72, which shows colours 0-3 as shades 2, 0, 3 and 1
+	if code.Remap(0x72) != tile {
+		t.Errorf("% X isn't % X shown with BGP $72", code, tile)
+	}
+
+	img.SetRGBA(0, 0, color.RGBA{R: 1, A: 0xFF})
+	img.SetRGBA(1, 0, color.RGBA{R: 2, A: 0xFF})
+	if _, _, err := EncodeCGBTile(img); err == nil {
+		t.Error("no error for a tile of more than 4 colours")
+	}
+
+	if _, _, err := EncodeCGBTile(image.NewRGBA(image.Rect(0, 0, 8, 7))); err == nil {
+		t.Error("no error for a tile of 8x7")
+	}
+}
+
+func TestPalettes(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	rom := &ROM{Data: make([]byte, 0x8000)}
+
+	var tiles []Tile
+	for i := 0; i < 16; i++ {
+		tiles = append(tiles, randomTile(rnd))
+		copy(rom.Data[0x4000+i*TileSize:], tiles[i][:])
+	}
+
+	// The top half of the screen in the first palette, the bottom half in the second one
+	img := image.NewRGBA(image.Rect(0, 0, gbScreenXRes, gbScreenYRes))
+	for y := 0; y < gbScreenYRes; y += 8 {
+		for x := 0; x < gbScreenXRes; x += 8 {
+			drawCGBTile(img, x, y, tiles[rnd.Intn(len(tiles))], cgbColours[y*2/gbScreenYRes])
+		}
+	}
+
+	// A sprite over the background makes a tile of more than 4 colours, which is skipped
+	img.SetRGBA(3, 3, color.RGBA{R: 0xFF, A: 0xFF})
+
+	s, err := NewScreenshot(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.CGB = true
+
+	matches, err := rom.FindScreenshot(s, Alignment{}, SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tilemap, err := s.Tilemap(Alignment{}, matches)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	palettes, numbers, err := s.Palettes(tilemap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(palettes) != 2 {
+		t.Fatalf("%d palettes, want 2: %v", len(palettes), palettes)
+	}
+
+	for n, palette := range palettes {
+		for i, c := range palette.Colours {
+			if !palette.Known[i] || c != cgbColours[n][i] {
+				t.Errorf("palette %d is %v, want colour %d to be %v", n, palette, i, cgbColours[n][i])
+			}
+		}
+	}
+
+	for j, entry := range tilemap.Entries {
+		want := entry.Y * 8 * 2 / gbScreenYRes
+		if j == 0 {
+			want = -1
+		}
+
+		if numbers[j] != want || entry.Found != (j != 0) {
+			t.Errorf("entry %d: palette %d, found %v, want palette %d", j, numbers[j], entry.Found, want)
+		}
+	}
+}
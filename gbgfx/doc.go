@@ -0,0 +1,18 @@
+// Package gbgfx extracts the graphics of a Gameboy ROM using a screenshot of the game.
+//
+// A Screenshot is chopped into 8x8 tiles, which are converted back to the 2BPP
+// format of the Gameboy (a Tile) and searched in the ROM. Every location where a
+// tile is found is a Match, telling how the screenshot was aligned to the
+// background grid, if the tile was flipped and which palette the game used.
+//
+//	rom, err := gbgfx.ReadROM("game.gb")
+//	...
+//	screenshot, err := gbgfx.LoadScreenshot("screen.png")
+//	...
+//	ranked, err := rom.RankAlignments(screenshot, gbgfx.SearchOptions{})
+//	matches, err := rom.FindScreenshot(screenshot, ranked[0].Align, gbgfx.SearchOptions{})
+//
+// The tiles of a VRAM dump (ReadVRAMDump) can be searched with ROM.FindVRAM, and
+// a BESS or mGBA save state (ReadSaveState) rebuilds the screen with its exact
+// alignment and palette.
+package gbgfx
@@ -0,0 +1,155 @@
+package gbgfx
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// DumpOptions tune how raw tile data is rendered, like a tile viewer
+type DumpOptions struct {
+	Columns int         // tiles per row
+	Rows    int         // rows per page, 0 for a single page
+	Palette TilePalette // colours of the 4 shades
+	Tall    bool        // 8x16 arrangement: every two tiles are drawn one above the other, like 8x16 sprites
+	Labels  bool        // print the BANK:ADDR of the first tile of every row on its left
+}
+
+// labelWidth is the space the BANK:ADDR labels take on the left of a page
+const labelWidth = len("00:0000")*(glyphWidth+1) + 2
+
+// DumpPage is a page of a dump, with the ROM offsets it shows
+type DumpPage struct {
+	Image image.Image
+	Start int
+	End   int // not included
+}
+
+// Dump renders the tile data in data, which starts at the ROM offset base, into pages
+func Dump(data []byte, base int, opts DumpOptions) ([]DumpPage, error) {
+	if opts.Columns < 1 {
+		return nil, errors.New("a dump needs at least 1 column")
+	}
+
+	if opts.Rows < 0 {
+		return nil, errors.New("the rows per page can't be negative")
+	}
+
+	if len(data) < TileSize {
+		return nil, errors.New("not a single tile to dump")
+	}
+
+	cellHeight := width
+	tilesPerCell := 1
+	if opts.Tall {
+		cellHeight, tilesPerCell = 2*width, 2
+	}
+
+	bytesPerRow := opts.Columns * tilesPerCell * TileSize
+	totalRows := (len(data) + bytesPerRow - 1) / bytesPerRow
+
+	rowsPerPage := opts.Rows
+	if rowsPerPage == 0 {
+		rowsPerPage = totalRows
+	}
+
+	left := 0
+	if opts.Labels {
+		left = labelWidth
+	}
+
+	var pages []DumpPage
+
+	for firstRow := 0; firstRow < totalRows; firstRow += rowsPerPage {
+		rows := totalRows - firstRow
+		if rows > rowsPerPage {
+			rows = rowsPerPage
+		}
+
+		img := image.NewRGBA(image.Rect(0, 0, left+opts.Columns*width, rows*cellHeight))
+		draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+		start := firstRow * bytesPerRow
+		end := start + rows*bytesPerRow
+		if end > len(data) {
+			end = len(data)
+		}
+
+		for offset := start; offset+TileSize <= end; offset += TileSize {
+			tile := (offset - start) / TileSize
+			cell := tile / tilesPerCell
+
+			x := left + (cell%opts.Columns)*width
+			y := (cell/opts.Columns)*cellHeight + (tile%tilesPerCell)*width
+
+			drawTile(img, x, y, data[offset:offset+TileSize], opts.Palette)
+		}
+
+		if opts.Labels {
+			for row := 0; row < rows; row++ {
+				drawText(img, 1, row*cellHeight+(width-glyphHeight)/2, BankAddress(base+start+row*bytesPerRow), color.Black)
+			}
+		}
+
+		pages = append(pages, DumpPage{Image: img, Start: base + start, End: base + end})
+	}
+
+	return pages, nil
+}
+
+// drawTile draws the 2BPP tile with its top left pixel at x,y, in the colours of the palette
+func drawTile(img *image.RGBA, x, y int, tile []byte, palette TilePalette) {
+	grey := TileImage(tile)
+
+	for py := 0; py < width; py++ {
+		for px := 0; px < width; px++ {
+			// TileImage draws shade n as 255*(3-n)/3
+			shade := 3 - grey.RGBAAt(px, py).R/0x55
+			img.SetRGBA(x+px, y+py, palette[shade])
+		}
+	}
+}
+
+const (
+	glyphWidth  = 3
+	glyphHeight = 5
+)
+
+// glyphs is a tiny font for the address labels, a row of 3 pixels per byte (bit 2 is the left one)
+var glyphs = map[rune][glyphHeight]byte{
+	'0': {0b111, 0b101, 0b101, 0b101, 0b111},
+	'1': {0b010, 0b110, 0b010, 0b010, 0b111},
+	'2': {0b111, 0b001, 0b111, 0b100, 0b111},
+	'3': {0b111, 0b001, 0b111, 0b001, 0b111},
+	'4': {0b101, 0b101, 0b111, 0b001, 0b001},
+	'5': {0b111, 0b100, 0b111, 0b001, 0b111},
+	'6': {0b111, 0b100, 0b111, 0b101, 0b111},
+	'7': {0b111, 0b001, 0b010, 0b010, 0b010},
+	'8': {0b111, 0b101, 0b111, 0b101, 0b111},
+	'9': {0b111, 0b101, 0b111, 0b001, 0b111},
+	'A': {0b010, 0b101, 0b111, 0b101, 0b101},
+	'B': {0b110, 0b101, 0b110, 0b101, 0b110},
+	'C': {0b011, 0b100, 0b100, 0b100, 0b011},
+	'D': {0b110, 0b101, 0b101, 0b101, 0b110},
+	'E': {0b111, 0b100, 0b110, 0b100, 0b111},
+	'F': {0b111, 0b100, 0b110, 0b100, 0b100},
+	':': {0b000, 0b010, 0b000, 0b010, 0b000},
+}
+
+// drawText draws the text with the tiny font, its top left pixel at x,y
+func drawText(img *image.RGBA, x, y int, text string, c color.Color) {
+	for _, r := range text {
+		glyph := glyphs[r]
+
+		for row, bits := range glyph {
+			for col := 0; col < glyphWidth; col++ {
+				if bits&(1<<(glyphWidth-1-col)) != 0 {
+					img.Set(x+col, y+row, c)
+				}
+			}
+		}
+
+		x += glyphWidth + 1
+	}
+}
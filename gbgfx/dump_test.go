@@ -0,0 +1,95 @@
+package gbgfx
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	var tiles []Tile
+	var data []byte
+	for i := 0; i < 10; i++ {
+		tiles = append(tiles, randomTile(rnd))
+		data = append(data, tiles[i][:]...)
+	}
+
+	palette, err := ParsePalette("bgb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tileAt checks that the tile is drawn with its top left pixel at x,y
+	tileAt := func(page image.Image, i, x, y int) {
+		for py := 0; py < 8; py++ {
+			for px := 0; px < 8; px++ {
+				if got, want := page.At(x+px, y+py), palette[pixel(tiles[i], px, py)]; got != want {
+					t.Fatalf("tile %d, pixel (%d,%d) is %v, want %v", i, px, py, got, want)
+				}
+			}
+		}
+	}
+
+	// 4 tiles per row and 2 rows per page: 8 tiles, then the last 2
+	pages, err := Dump(data, 0x4000, DumpOptions{Columns: 4, Rows: 2, Palette: palette})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pages) != 2 {
+		t.Fatalf("%d pages, want 2", len(pages))
+	}
+
+	if pages[0].Start != 0x4000 || pages[0].End != 0x4080 || pages[1].Start != 0x4080 || pages[1].End != 0x40A0 {
+		t.Errorf("pages 0x%X-0x%X and 0x%X-0x%X", pages[0].Start, pages[0].End, pages[1].Start, pages[1].End)
+	}
+
+	if pages[0].Image.Bounds().Size() != image.Pt(32, 16) || pages[1].Image.Bounds().Size() != image.Pt(32, 8) {
+		t.Errorf("pages of %v and %v", pages[0].Image.Bounds().Size(), pages[1].Image.Bounds().Size())
+	}
+
+	tileAt(pages[0].Image, 5, 8, 8)
+	tileAt(pages[1].Image, 9, 8, 0)
+
+	// In 8x16, every two tiles are one above the other, and the labels push the tiles to the right
+	pages, err = Dump(data, 0x4000, DumpOptions{Columns: 2, Palette: palette, Tall: true, Labels: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pages) != 1 || pages[0].Image.Bounds().Size() != image.Pt(labelWidth+16, 48) {
+		t.Fatalf("%d pages, the first one of %v", len(pages), pages[0].Image.Bounds().Size())
+	}
+
+	tileAt(pages[0].Image, 6, labelWidth+8, 16)
+	tileAt(pages[0].Image, 7, labelWidth+8, 24)
+	tileAt(pages[0].Image, 8, labelWidth, 32)
+
+	// The label of the first row is "01:4000", whose 0 starts with a line
+	if got := pages[0].Image.At(1, (8-glyphHeight)/2); got != (color.RGBA{A: 0xFF}) {
+		t.Errorf("the label of the first row starts with %v, want black", got)
+	}
+}
+
+func TestDumpErrors(t *testing.T) {
+	data := make([]byte, 2*TileSize)
+
+	tests := []struct {
+		name string
+		data []byte
+		opts DumpOptions
+	}{
+		{"no columns", data, DumpOptions{}},
+		{"negative rows", data, DumpOptions{Columns: 1, Rows: -1}},
+		{"less than a tile", data[:TileSize-1], DumpOptions{Columns: 1}},
+	}
+
+	for _, test := range tests {
+		if _, err := Dump(test.data, 0, test.opts); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package gbgfx
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+)
+
+func readImageFromFilePath(path string) (image.Image, error) {
+	// Load the screenshot
+	infile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer infile.Close()
+
+	// Decode the image
+	imData, imType, err := image.Decode(infile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	// Make sure it's a PNG
+	if imType != "png" {
+		return nil, fmt.Errorf("%s is not a PNG, it is %s", path, imType)
+	}
+
+	return imData, nil
+}
+
+// LoadImage reads a PNG and converts it to RGBA, whatever colour model the
+// image editor saved it with, so its tiles can be encoded with EncodeTile
+func LoadImage(path string) (*image.RGBA, error) {
+	imData, err := readImageFromFilePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, imData.Bounds().Dx(), imData.Bounds().Dy()))
+	draw.Draw(img, img.Bounds(), imData, imData.Bounds().Min, draw.Src)
+
+	return img, nil
+}
+
+func saveUniqueTiles(tiles []image.Image) []image.Image {
+	uniqueTiles := []image.Image{}
+
+	for _, tile := range tiles {
+		isUnique := true
+		for j := 0; j < len(uniqueTiles); j++ {
+			if areImagesEquivalent(tile, uniqueTiles[j]) {
+				isUnique = false
+				break
+			}
+		}
+
+		if isUnique {
+			uniqueTiles = append(uniqueTiles, tile)
+		}
+	}
+
+	return uniqueTiles
+}
+
+// SavePNG encodes the image as PNG and saves it to outputFilename
+func SavePNG(outputFilename string, img image.Image) error {
+	f, err := os.Create(outputFilename)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	defer func(f *os.File) {
+		if err := f.Close(); err != nil {
+			return
+		}
+	}(f)
+
+	err = png.Encode(f, img)
+	if err != nil {
+		return fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return nil
+}
@@ -1,13 +1,8 @@
-package main
+package gbgfx
 
 import (
-	"fmt"
 	"image"
 	"image/color"
-	"image/png"
-	"os"
-	"strconv"
-	"strings"
 )
 
 // convert2BPPToPNG converts a 2BPP tile to a PNG image
@@ -43,51 +38,15 @@ func convert2BPPToPNG(height int, tile []byte, img *image.RGBA, xPos int, yPos i
 	}
 }
 
-// convertHexToInt32 converts a hex string to an int32
-func convertHexToInt32(v string) int32 {
-	startOffsetString := v
-	// Remove the 0x prefix and keep only the actual number (as a string)
-	if !strings.Contains(startOffsetString, "0x") {
-		fmt.Println("Invalid start offset specified! Please specify a HEX value (e.g. 0x3f)")
-		os.Exit(1)
-	}
-	// Remove the 0x prefix (it's not needed anymore)
-	startOffsetString = strings.ReplaceAll(startOffsetString, "0x", "")
-	// and convert the strings (which represent a hex value) to a decimal int32
-	a, _ := strconv.ParseInt(startOffsetString, 16, 32)
-
-	return int32(a)
-}
-
-func saveToDisk(outputFilename string, img image.Image) error {
-	f, err := os.Create(outputFilename)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
+// Remove duplicate tiles
+func removeDuplicateTiles(tiles []Tile) []Tile {
+	allKeys := make(map[Tile]bool)
 
-	defer func(f *os.File) {
-		if err := f.Close(); err != nil {
-			return
-		}
-	}(f)
-
-	err = png.Encode(f, img)
-	if err != nil {
-		return fmt.Errorf("failed to encode image: %w", err)
-	}
-
-	return nil
-}
+	var list []Tile
 
-// Remove duplicates [][]byte
-func removeDuplicateByte(byteSlice [][]byte) [][]byte {
-	allKeys := make(map[string]bool)
-
-	var list [][]byte
-
-	for _, item := range byteSlice {
-		if _, value := allKeys[string(item)]; !value {
-			allKeys[string(item)] = true
+	for _, item := range tiles {
+		if _, value := allKeys[item]; !value {
+			allKeys[item] = true
 
 			list = append(list, item)
 		}
@@ -96,13 +55,18 @@ func removeDuplicateByte(byteSlice [][]byte) [][]byte {
 	return list
 }
 
-func getHexCodes(tiles []image.Image) [][]byte {
-	origCodeTiles := make([][]byte, 0)
+func getHexCodes(tiles []image.Image, encode func(image.Image) (Tile, error)) ([]Tile, error) {
+	origCodeTiles := make([]Tile, 0)
 	for _, tile := range tiles {
-		origCodeTiles = append(origCodeTiles, pngTo2BPP(tile))
+		code, err := encode(tile)
+		if err != nil {
+			return nil, err
+		}
+
+		origCodeTiles = append(origCodeTiles, code)
 	}
 
-	return origCodeTiles
+	return origCodeTiles, nil
 }
 
 func compare(tile []byte, code []byte) bool {
@@ -178,15 +142,3 @@ func areImagesEquivalent(img1, img2 image.Image) bool {
 	flippedBoth := flipImageVertically(flippedHorizontally)
 	return areImagesEqual(flippedBoth, img2)
 }
-
-func removeDuplicateString(slice []string) []string {
-	keys := make(map[string]bool)
-	list := []string{}
-	for _, entry := range slice {
-		if _, value := keys[entry]; !value {
-			keys[entry] = true
-			list = append(list, entry)
-		}
-	}
-	return list
-}
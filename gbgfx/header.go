@@ -0,0 +1,247 @@
+package gbgfx
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Offsets of the cartridge header fields in the ROM
+const (
+	logoOffset           = 0x104
+	titleOffset          = 0x134
+	cgbFlagOffset        = 0x143
+	sgbFlagOffset        = 0x146
+	cartridgeTypeOffset  = 0x147
+	romSizeOffset        = 0x148
+	ramSizeOffset        = 0x149
+	headerChecksumOffset = 0x14D
+	globalChecksumOffset = 0x14E
+	headerEnd            = 0x150
+)
+
+// ROMBankSize is the size of a ROM bank, bank 0 is mapped at $0000-$3FFF and
+// the switchable banks at $4000-$7FFF of the CPU address space
+const ROMBankSize = 0x4000
+
+// nintendoLogo is the bitmap the boot ROM compares before starting the game
+var nintendoLogo = []byte{
+	0xCE, 0xED, 0x66, 0x66, 0xCC, 0x0D, 0x00, 0x0B, 0x03, 0x73, 0x00, 0x83, 0x00, 0x0C, 0x00, 0x0D,
+	0x00, 0x08, 0x11, 0x1F, 0x88, 0x89, 0x00, 0x0E, 0xDC, 0xCC, 0x6E, 0xE6, 0xDD, 0xDD, 0xD9, 0x99,
+	0xBB, 0xBB, 0x67, 0x63, 0x6E, 0x0E, 0xEC, 0xCC, 0xDD, 0xDC, 0x99, 0x9F, 0xBB, 0xB9, 0x33, 0x3E,
+}
+
+// cartridgeTypes maps the cartridge type byte to the hardware on the cartridge
+var cartridgeTypes = map[byte]string{
+	0x00: "ROM ONLY",
+	0x01: "MBC1",
+	0x02: "MBC1+RAM",
+	0x03: "MBC1+RAM+BATTERY",
+	0x05: "MBC2",
+	0x06: "MBC2+BATTERY",
+	0x08: "ROM+RAM",
+	0x09: "ROM+RAM+BATTERY",
+	0x0B: "MMM01",
+	0x0C: "MMM01+RAM",
+	0x0D: "MMM01+RAM+BATTERY",
+	0x0F: "MBC3+TIMER+BATTERY",
+	0x10: "MBC3+TIMER+RAM+BATTERY",
+	0x11: "MBC3",
+	0x12: "MBC3+RAM",
+	0x13: "MBC3+RAM+BATTERY",
+	0x19: "MBC5",
+	0x1A: "MBC5+RAM",
+	0x1B: "MBC5+RAM+BATTERY",
+	0x1C: "MBC5+RUMBLE",
+	0x1D: "MBC5+RUMBLE+RAM",
+	0x1E: "MBC5+RUMBLE+RAM+BATTERY",
+	0x20: "MBC6",
+	0x22: "MBC7+SENSOR+RUMBLE+RAM+BATTERY",
+	0xFC: "POCKET CAMERA",
+	0xFD: "BANDAI TAMA5",
+	0xFE: "HuC3",
+	0xFF: "HuC1+RAM+BATTERY",
+}
+
+// ramSizes maps the RAM size byte to the size of the cartridge RAM in KB
+var ramSizes = map[byte]int{
+	0x00: 0,
+	0x01: 2,
+	0x02: 8,
+	0x03: 32,
+	0x04: 128,
+	0x05: 64,
+}
+
+// Header is the information stored at $0100-$014F of every ROM
+type Header struct {
+	Title          string
+	LogoOK         bool // the Nintendo logo is intact
+	CGBFlag        byte
+	SGBFlag        byte
+	CartridgeType  byte
+	ROMSize        byte
+	RAMSize        byte
+	HeaderChecksum byte
+	GlobalChecksum uint16
+
+	// The checksums as computed from the ROM, to validate the stored ones
+	ComputedHeaderChecksum byte
+	ComputedGlobalChecksum uint16
+}
+
+// ParseHeader reads the cartridge header of the ROM
+func ParseHeader(rom []byte) (Header, error) {
+	if len(rom) < headerEnd {
+		return Header{}, fmt.Errorf("ROM is too small (%d bytes) to have a cartridge header", len(rom))
+	}
+
+	h := Header{
+		LogoOK:         bytes.Equal(rom[logoOffset:logoOffset+len(nintendoLogo)], nintendoLogo),
+		CGBFlag:        rom[cgbFlagOffset],
+		SGBFlag:        rom[sgbFlagOffset],
+		CartridgeType:  rom[cartridgeTypeOffset],
+		ROMSize:        rom[romSizeOffset],
+		RAMSize:        rom[ramSizeOffset],
+		HeaderChecksum: rom[headerChecksumOffset],
+		GlobalChecksum: uint16(rom[globalChecksumOffset])<<8 | uint16(rom[globalChecksumOffset+1]),
+
+		ComputedHeaderChecksum: HeaderChecksum(rom),
+		ComputedGlobalChecksum: GlobalChecksum(rom),
+	}
+
+	// On CGB cartridges the last byte of the title is the CGB flag
+	title := rom[titleOffset : cgbFlagOffset+1]
+	if h.CGBFlag&0x80 != 0 {
+		title = rom[titleOffset:cgbFlagOffset]
+	}
+
+	// Keep the title printable, since homebrew and corrupted ROMs may have anything there
+	h.Title = strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' {
+			return '.'
+		}
+
+		return r
+	}, strings.TrimRight(string(bytes.TrimRight(title, "\x00")), " "))
+
+	return h, nil
+}
+
+// HeaderChecksum computes the checksum of $0134-$014C, which the boot ROM verifies
+func HeaderChecksum(rom []byte) byte {
+	var sum byte
+
+	for _, b := range rom[titleOffset:headerChecksumOffset] {
+		sum = sum - b - 1
+	}
+
+	return sum
+}
+
+// GlobalChecksum computes the sum of every byte of the ROM except the global checksum itself
+func GlobalChecksum(rom []byte) uint16 {
+	var sum uint16
+
+	for i, b := range rom {
+		if i != globalChecksumOffset && i != globalChecksumOffset+1 {
+			sum += uint16(b)
+		}
+	}
+
+	return sum
+}
+
+// CGBSupport describes the CGB flag
+func (h Header) CGBSupport() string {
+	switch h.CGBFlag {
+	case 0x80:
+		return "CGB enhanced"
+	case 0xC0:
+		return "CGB only"
+	default:
+		return "DMG"
+	}
+}
+
+// SGBSupport describes the SGB flag
+func (h Header) SGBSupport() string {
+	if h.SGBFlag == 0x03 {
+		return "SGB enhanced"
+	}
+
+	return "no SGB functions"
+}
+
+// ROMBanks returns the number of 16 KB ROM banks declared in the header, or 0 if unknown
+func (h Header) ROMBanks() int {
+	switch {
+	case h.ROMSize <= 0x08:
+		return 2 << h.ROMSize
+	case h.ROMSize == 0x52:
+		return 72
+	case h.ROMSize == 0x53:
+		return 80
+	case h.ROMSize == 0x54:
+		return 96
+	default:
+		return 0
+	}
+}
+
+// Warnings validates the header against the ROM and returns what's wrong with it
+func (h Header) Warnings(romLength int) []string {
+	var warnings []string
+
+	if !h.LogoOK {
+		warnings = append(warnings, "the Nintendo logo doesn't match, this may not be a Gameboy ROM")
+	}
+
+	if h.HeaderChecksum != h.ComputedHeaderChecksum {
+		warnings = append(warnings, fmt.Sprintf("header checksum is $%02X, but should be $%02X", h.HeaderChecksum, h.ComputedHeaderChecksum))
+	}
+
+	if h.GlobalChecksum != h.ComputedGlobalChecksum {
+		warnings = append(warnings, fmt.Sprintf("global checksum is $%04X, but should be $%04X", h.GlobalChecksum, h.ComputedGlobalChecksum))
+	}
+
+	if _, ok := cartridgeTypes[h.CartridgeType]; !ok {
+		warnings = append(warnings, fmt.Sprintf("unknown cartridge type $%02X", h.CartridgeType))
+	}
+
+	if banks := h.ROMBanks(); banks == 0 {
+		warnings = append(warnings, fmt.Sprintf("unknown ROM size $%02X", h.ROMSize))
+	} else if banks*ROMBankSize != romLength {
+		warnings = append(warnings, fmt.Sprintf("header declares %d KB of ROM, but the file is %d KB", banks*ROMBankSize/1024, romLength/1024))
+	}
+
+	if _, ok := ramSizes[h.RAMSize]; !ok {
+		warnings = append(warnings, fmt.Sprintf("unknown RAM size $%02X", h.RAMSize))
+	}
+
+	return warnings
+}
+
+// CartridgeTypeName returns the hardware on the cartridge (e.g. MBC3+RAM+BATTERY),
+// or false if the cartridge type is unknown
+func (h Header) CartridgeTypeName() (string, bool) {
+	name, ok := cartridgeTypes[h.CartridgeType]
+	return name, ok
+}
+
+// RAMSizeKB returns the size of the cartridge RAM in KB, or false if the RAM size is unknown
+func (h Header) RAMSizeKB() (int, bool) {
+	size, ok := ramSizes[h.RAMSize]
+	return size, ok
+}
+
+// BankAddress converts an offset of the ROM file to BANK:ADDR, the way the game
+// sees it in the CPU address space: bank 0 at $0000-$3FFF and every other bank at $4000-$7FFF
+func BankAddress(offset int) string {
+	bank, addr := offset/ROMBankSize, offset%ROMBankSize
+	if bank > 0 {
+		addr += ROMBankSize
+	}
+
+	return fmt.Sprintf("%02X:%04X", bank, addr)
+}
@@ -0,0 +1,55 @@
+package gbgfx
+
+import "testing"
+
+func TestHeaderChecksum(t *testing.T) {
+	filled := func(b byte) []byte {
+		rom := make([]byte, 0x8000)
+		for i := range rom {
+			rom[i] = b
+		}
+
+		return rom
+	}
+
+	title := filled(0)
+	copy(title[titleOffset:], "TETRIS")
+
+	tests := []struct {
+		name string
+		rom  []byte
+		want byte
+	}{
+		// 25 bytes from $0134 to $014C, each subtracted plus one
+		{"zeros", filled(0), 0xE7},
+		{"0xFF", filled(0xFF), 0x00},
+		{"title", title, 0x0C}, // 0xE7 minus the letters of TETRIS
+	}
+
+	for _, test := range tests {
+		if got := HeaderChecksum(test.rom); got != test.want {
+			t.Errorf("%s: $%02X, want $%02X", test.name, got, test.want)
+		}
+	}
+}
+
+func TestGlobalChecksum(t *testing.T) {
+	rom := make([]byte, 0x8000)
+	for i := range rom {
+		rom[i] = 1
+	}
+
+	// The two bytes of the checksum itself don't count
+	if got := GlobalChecksum(rom); got != 0x7FFE {
+		t.Errorf("ones: $%04X, want $7FFE", got)
+	}
+
+	for i := range rom {
+		rom[i] = 0xFF
+	}
+
+	// 0xFF * 0x7FFE, modulo 0x10000
+	if got := GlobalChecksum(rom); got != 0x7E02 {
+		t.Errorf("0xFF: $%04X, want $7E02", got)
+	}
+}
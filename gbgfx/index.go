@@ -0,0 +1,91 @@
+package gbgfx
+
+import "sort"
+
+// Index finds tiles in a ROM without scanning the whole ROM for every tile.
+// It holds a hash of every 16-byte window of the ROM (i.e. a tile starting at
+// any offset), packed together with the offset of the window as hash<<32 | offset,
+// and sorted. So all the windows with the same bytes end up next to each other,
+// ordered by offset, and a tile is found with a binary search.
+type Index struct {
+	rom     []byte
+	entries []uint64
+}
+
+// NewIndex hashes every 16-byte window of the ROM in a single pass
+func NewIndex(rom []byte) *Index {
+	var entries []uint64
+
+	if len(rom) >= rangeLength {
+		entries = make([]uint64, len(rom)-rangeLength+1)
+		for offset := range entries {
+			entries[offset] = uint64(hashTile(rom[offset:offset+rangeLength]))<<32 | uint64(offset)
+		}
+
+		radixSort(entries)
+	}
+
+	return &Index{rom: rom, entries: entries}
+}
+
+// Lookup returns the offsets of the ROM where tile is stored, in ascending order
+func (idx *Index) Lookup(tile Tile) []int {
+	hash := uint64(hashTile(tile[:]))
+	first := sort.Search(len(idx.entries), func(i int) bool {
+		return idx.entries[i]>>32 >= hash
+	})
+
+	var offsets []int
+
+	for i := first; i < len(idx.entries) && idx.entries[i]>>32 == hash; i++ {
+		offset := int(idx.entries[i] & 0xFFFFFFFF)
+
+		// Different windows can have the same hash, so compare the actual bytes
+		if compare(tile[:], idx.rom[offset:offset+rangeLength]) {
+			offsets = append(offsets, offset)
+		}
+	}
+
+	return offsets
+}
+
+// hashTile hashes the bytes of a tile with 32-bit FNV-1a
+func hashTile(tile []byte) uint32 {
+	hash := uint32(2166136261)
+
+	for _, b := range tile {
+		hash ^= uint32(b)
+		hash *= 16777619
+	}
+
+	return hash
+}
+
+// radixSort sorts the entries in ascending order, one byte at a time.
+// It's a lot faster than sort.Slice for the millions of entries of a big ROM.
+func radixSort(entries []uint64) {
+	buffer := make([]uint64, len(entries))
+	src, dst := entries, buffer
+
+	for shift := 0; shift < 64; shift += 8 {
+		var counts [257]int
+
+		for _, entry := range src {
+			counts[(entry>>shift)&0xFF+1]++
+		}
+
+		for i := 1; i < len(counts); i++ {
+			counts[i] += counts[i-1]
+		}
+
+		for _, entry := range src {
+			bucket := (entry >> shift) & 0xFF
+			dst[counts[bucket]] = entry
+			counts[bucket]++
+		}
+
+		src, dst = dst, src
+	}
+
+	// 8 passes is an even number, so the sorted entries are back in the original slice
+}
@@ -0,0 +1,78 @@
+package gbgfx
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// naiveLookup compares the tile with every 16-byte window of the ROM
+func naiveLookup(rom []byte, tile Tile) []int {
+	var offsets []int
+
+	for offset := 0; offset+TileSize <= len(rom); offset++ {
+		if bytes.Equal(rom[offset:offset+TileSize], tile[:]) {
+			offsets = append(offsets, offset)
+		}
+	}
+
+	return offsets
+}
+
+func TestIndexLookup(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	// Random bytes, with a few tiles stored several times and runs of the same byte
+	rom := make([]byte, 0x10000)
+	rnd.Read(rom)
+
+	var tiles []Tile
+	for i := 0; i < 8; i++ {
+		var tile Tile
+		rnd.Read(tile[:])
+		tiles = append(tiles, tile)
+
+		for j := 0; j <= i; j++ {
+			copy(rom[rnd.Intn(len(rom)-TileSize):], tile[:])
+		}
+	}
+
+	for i := 0x8000; i < 0x8100; i++ {
+		rom[i] = 0xFF
+	}
+
+	tiles = append(tiles, Tile{}, Tile{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})
+
+	for i := 0; i < 32; i++ {
+		var tile Tile
+		copy(tile[:], rom[rnd.Intn(len(rom)-TileSize):])
+		tiles = append(tiles, tile)
+	}
+
+	index := NewIndex(rom)
+
+	for _, tile := range tiles {
+		got, want := index.Lookup(tile), naiveLookup(rom, tile)
+		if len(got) != len(want) {
+			t.Errorf("% X: %d offsets, want %d", tile, len(got), len(want))
+			continue
+		}
+
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("% X: offsets %X, want %X", tile, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestIndexShortROM(t *testing.T) {
+	for _, size := range []int{0, TileSize - 1, TileSize} {
+		rom := make([]byte, size)
+
+		if got, want := NewIndex(rom).Lookup(Tile{}), naiveLookup(rom, Tile{}); len(got) != len(want) {
+			t.Errorf("%d bytes: %d offsets, want %d", size, len(got), len(want))
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package gbgfx
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// mGBA saves the GBSerializedState struct of its gb/serialize.h as it is, in little
+// endian, followed by extra data. With screenshots on (the default) the file is a
+// PNG of the screen instead, with the state compressed with zlib in a gbAs chunk.
+const (
+	mgbaMagic     = 0x00400000 // the version is added to it
+	mgbaMagicMask = 0xFFF00000
+	mgbaChunk     = "gbAs"
+	mgbaMaxSize   = 1 << 20 // the state is 70 KB, the rest of a chunk can't be it
+
+	// Offsets in the state
+	mgbaModel    = 0x008
+	mgbaPalettes = 0x0C0 // 64 colours in RGB555, the 8 BG palettes first
+	mgbaIO       = 0x198 // $FF00-$FF7F
+	mgbaOAM      = 0x400
+	mgbaVRAM     = 0x4A0 // both banks, the second one is unused on the DMG
+	mgbaSize     = mgbaVRAM + 2*VRAMBankSize
+)
+
+// mgbaModels maps the models of mGBA to the ones of BESS, which SaveState uses
+var mgbaModels = map[byte]string{
+	0x00: "GD  ", // DMG
+	0x20: "SN  ", // SGB
+	0x40: "GM  ", // MGB
+	0x60: "S2  ", // SGB2
+	0x80: "CC  ", // CGB
+	0xA0: "CC  ", // CGB with the SGB functions
+	0xC0: "CA  ", // AGB
+}
+
+// pngSignature starts every PNG file
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// isMGBA tells if the data starts with the magic number of an mGBA save state
+func isMGBA(data []byte) bool {
+	return len(data) >= 4 && binary.LittleEndian.Uint32(data)&mgbaMagicMask == mgbaMagic
+}
+
+// mgbaPNGState returns the save state stored in the gbAs chunk of a PNG saved by mGBA
+func mgbaPNGState(data []byte) ([]byte, error) {
+	// Every chunk is its length (4 bytes, big endian), its name, its data and a CRC32
+	for p := len(pngSignature); p+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[p:]))
+		name := string(data[p+4 : p+8])
+
+		if length < 0 || p+12+length > len(data) {
+			return nil, fmt.Errorf("PNG chunk %q is cut short", name)
+		}
+
+		if name == mgbaChunk {
+			r, err := zlib.NewReader(bytes.NewReader(data[p+8 : p+8+length]))
+			if err != nil {
+				return nil, fmt.Errorf("mGBA save state: %w", err)
+			}
+			defer r.Close()
+
+			state, err := io.ReadAll(io.LimitReader(r, mgbaMaxSize))
+			if err != nil {
+				return nil, fmt.Errorf("mGBA save state: %w", err)
+			}
+
+			return state, nil
+		}
+
+		p += 12 + length
+	}
+
+	return nil, errors.New("this is a PNG without an mGBA save state in it, like a screenshot (use --img)")
+}
+
+// parseMGBA reads the model, the video registers and the memory of an mGBA save state
+func parseMGBA(data []byte) (*SaveState, error) {
+	if !isMGBA(data) {
+		return nil, errors.New("not an mGBA save state")
+	}
+
+	if len(data) < mgbaSize {
+		return nil, fmt.Errorf("mGBA save state is %d bytes, expected at least %d", len(data), mgbaSize)
+	}
+
+	model, ok := mgbaModels[data[mgbaModel]]
+	if !ok {
+		return nil, fmt.Errorf("mGBA model $%02X isn't supported", data[mgbaModel])
+	}
+
+	regs := data[mgbaIO:]
+	s := &SaveState{
+		Model:       model,
+		OAM:         data[mgbaOAM : mgbaOAM+oamEntries*oamEntrySize],
+		BGPalettes:  data[mgbaPalettes : mgbaPalettes+8*cgbPaletteSize],
+		OBJPalettes: data[mgbaPalettes+8*cgbPaletteSize : mgbaPalettes+16*cgbPaletteSize],
+		LCDC:        regs[regLCDC],
+		SCX:         regs[regSCX],
+		SCY:         regs[regSCY],
+		WX:          regs[regWX],
+		WY:          regs[regWY],
+		BGP:         regs[regBGP],
+		OBP0:        regs[regOBP0],
+		OBP1:        regs[regOBP1],
+	}
+
+	vram := data[mgbaVRAM:mgbaSize]
+	if model[0] != 'C' {
+		vram = vram[:VRAMBankSize]
+	}
+
+	var err error
+	if s.VRAM, err = ParseVRAMDump(vram); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
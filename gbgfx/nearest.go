@@ -0,0 +1,165 @@
+package gbgfx
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Metric is how far apart two colours are
+type Metric int
+
+const (
+	MetricRGB Metric = iota // Euclidean distance of the 8-bit channels
+	MetricLab               // Euclidean distance in CIELAB, closer to how different the colours look
+)
+
+// ParseMetric parses rgb or lab
+func ParseMetric(name string) (Metric, error) {
+	switch name {
+	case "rgb":
+		return MetricRGB, nil
+	case "lab":
+		return MetricLab, nil
+	default:
+		return 0, fmt.Errorf("unknown colour distance %q, use rgb or lab", name)
+	}
+}
+
+// DefaultMaxDistance is the farthest a pixel may be from the palette, if no other is given:
+// half the smallest distance between two colours of shades that are different shades.
+// A pixel any farther may be closer to the wrong shade than to its own.
+func (m Metric) DefaultMaxDistance(shades ShadeMap) float64 {
+	smallest := math.Inf(1)
+
+	for a, shadeA := range shades {
+		for b, shadeB := range shades {
+			if shadeA != shadeB {
+				smallest = math.Min(smallest, m.Distance(a, b))
+			}
+		}
+	}
+
+	return smallest / 2
+}
+
+// Distance returns how far apart the two colours are
+func (m Metric) Distance(a, b color.RGBA) float64 {
+	if m == MetricLab {
+		l1, a1, b1 := toLab(a)
+		l2, a2, b2 := toLab(b)
+
+		return math.Sqrt((l1-l2)*(l1-l2) + (a1-a2)*(a1-a2) + (b1-b2)*(b1-b2))
+	}
+
+	dr, dg, db := float64(a.R)-float64(b.R), float64(a.G)-float64(b.G), float64(a.B)-float64(b.B)
+
+	return math.Sqrt(dr*dr + dg*dg + db*db)
+}
+
+// toLab converts an sRGB colour to CIELAB, with the D65 white point
+func toLab(c color.RGBA) (float64, float64, float64) {
+	linear := func(v uint8) float64 {
+		x := float64(v) / 255
+		if x <= 0.04045 {
+			return x / 12.92
+		}
+
+		return math.Pow((x+0.055)/1.055, 2.4)
+	}
+
+	r, g, b := linear(c.R), linear(c.G), linear(c.B)
+
+	x := (0.4124*r + 0.3576*g + 0.1805*b) / 0.95047
+	y := 0.2126*r + 0.7152*g + 0.0722*b
+	z := (0.0193*r + 0.1192*g + 0.9505*b) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+
+		return (24389.0/27*t + 16) / 116
+	}
+
+	fx, fy, fz := f(x), f(y), f(z)
+
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+// DefaultShades are the colours EncodeTile takes: the 4 greys and the palette of BGB
+func DefaultShades() ShadeMap {
+	shades := GreyPalette.Shades()
+
+	bgb, _ := ParsePalette("bgb")
+	for c, shade := range bgb.Shades() {
+		shades[c] = shade
+	}
+
+	return shades
+}
+
+// SnapReport tells how much a screenshot was changed to fit the palette
+type SnapReport struct {
+	Snapped     int     // pixels whose colour wasn't in the palette
+	MaxDistance float64 // the farthest any pixel was from its colour in the palette
+}
+
+// SnapColours redraws a DMG screenshot in the greys of TileImage, like ApplyShades,
+// but the colours that aren't in shades take the shade of the nearest colour that is.
+// Screenshots from videos, forums or emulators with colour correction rarely have the
+// exact colours of the palette. A pixel farther than maxDistance from every colour of
+// the palette is an error, since it can't be told which shade it is.
+func (s *Screenshot) SnapColours(shades ShadeMap, metric Metric, maxDistance float64) (SnapReport, error) {
+	var report SnapReport
+
+	type snap struct {
+		shade    byte
+		distance float64
+	}
+
+	nearest := make(map[color.RGBA]snap)
+
+	bounds := s.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c := color.RGBAModel.Convert(s.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA)
+
+			if shade, ok := shades[c]; ok {
+				img.SetRGBA(x, y, GreyPalette[shade])
+				continue
+			}
+
+			found, ok := nearest[c]
+			if !ok {
+				found.distance = math.Inf(1)
+
+				for paletteColour, shade := range shades {
+					// The lighter shade wins a tie, whatever order the map is in
+					if d := metric.Distance(c, paletteColour); d < found.distance || (d == found.distance && shade < found.shade) {
+						found = snap{shade: shade, distance: d}
+					}
+				}
+
+				nearest[c] = found
+			}
+
+			if found.distance > maxDistance {
+				return report, fmt.Errorf("colour #%02X%02X%02X at pixel (%d,%d) is %.1f away from the palette, more than %.1f",
+					c.R, c.G, c.B, x, y, found.distance, maxDistance)
+			}
+
+			report.Snapped++
+			report.MaxDistance = math.Max(report.MaxDistance, found.distance)
+
+			img.SetRGBA(x, y, GreyPalette[found.shade])
+		}
+	}
+
+	s.Image = img
+
+	return report, nil
+}
@@ -0,0 +1,32 @@
+package gbgfx
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDefaultMaxDistance(t *testing.T) {
+	original, err := ParsePalette("original")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		shades ShadeMap
+		want   float64
+	}{
+		// #9BBC0F and #8BAC0F are the closest shades of the original palette
+		{"original", original.Shades(), math.Sqrt(16*16+16*16) / 2},
+		// The greys are 0x55 apart in every channel
+		{"greys", GreyPalette.Shades(), math.Sqrt(3*0x55*0x55) / 2},
+		// Colours of the same shade don't count, only the ones of different shades
+		{"same shade", ShadeMap{{0xFF, 0xFF, 0xFF, 0xFF}: 0, {0xFE, 0xFE, 0xFE, 0xFF}: 0, {0, 0, 0, 0xFF}: 3}, math.Sqrt(3*0xFE*0xFE) / 2},
+	}
+
+	for _, test := range tests {
+		if got := MetricRGB.DefaultMaxDistance(test.shades); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: %.2f, want %.2f", test.name, got, test.want)
+		}
+	}
+}
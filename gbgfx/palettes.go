@@ -0,0 +1,305 @@
+package gbgfx
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	PaletteGreyscale = iota
+	PaletteOriginal
+	PaletteBGB
+)
+
+// paletteNames are the names of the Palettes, for the command line
+var paletteNames = map[string]int{
+	"greyscale": PaletteGreyscale,
+	"original":  PaletteOriginal,
+	"bgb":       PaletteBGB,
+}
+
+const (
+	lightest = byte(iota)
+	light
+	dark
+	darkest
+)
+
+// Palettes is a mapping from colour palettes to their colour values
+// to be used by the emulator.
+var Palettes = [][][]byte{
+	// PaletteGreyscale
+	{
+		{0xFF, 0xFF, 0xFF},
+		{0xCC, 0xCC, 0xCC},
+		{0x77, 0x77, 0x77},
+		{0x00, 0x00, 0x00},
+	},
+	// PaletteOriginal
+	{
+		{0x9B, 0xBC, 0x0F},
+		{0x8B, 0xAC, 0x0F},
+		{0x30, 0x62, 0x30},
+		{0x0F, 0x38, 0x0F},
+	},
+	// PaletteBGB
+	{
+		{0xE0, 0xF8, 0xD0}, // lightest (#E0F8D0)
+		{0x88, 0xC0, 0x70}, // light (#88C070)
+		{0x34, 0x68, 0x56}, // dark (#346856)
+		{0x08, 0x18, 0x20}, // darkest (#081820)
+	},
+}
+
+// GetPaletteColour returns the colour based on the colour index and the currently
+// selected palette.
+func GetPaletteColour(index byte, palette byte) (uint8, uint8, uint8) {
+	col := Palettes[palette][index]
+	r, g, b := col[0], col[1], col[2]
+
+	return r, g, b
+}
+
+// TilePalette is the colour of each of the 4 shades, lightest first
+type TilePalette [4]color.RGBA
+
+// GreyPalette is the palette of the tiles saved by TileImage
+var GreyPalette = TilePalette{
+	{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+	{R: 0xAA, G: 0xAA, B: 0xAA, A: 0xFF},
+	{R: 0x55, G: 0x55, B: 0x55, A: 0xFF},
+	{R: 0x00, G: 0x00, B: 0x00, A: 0xFF},
+}
+
+// ParsePalette returns the palette with the given name: grey (the one of TileImage),
+// greyscale, original or bgb. Any other palette is given as its 4 colours in hex,
+// lightest first, e.g. E0F8D0,88C070,346856,081820.
+func ParsePalette(name string) (TilePalette, error) {
+	if name == "grey" {
+		return GreyPalette, nil
+	}
+
+	if strings.Contains(name, ",") {
+		return parseHexPalette(name)
+	}
+
+	index, ok := paletteNames[name]
+	if !ok {
+		return TilePalette{}, fmt.Errorf("unknown palette %q, use grey, greyscale, original, bgb or 4 hex colours", name)
+	}
+
+	var palette TilePalette
+	for shade := range palette {
+		r, g, b := GetPaletteColour(byte(shade), byte(index))
+		palette[shade] = color.RGBA{R: r, G: g, B: b, A: 0xFF}
+	}
+
+	return palette, nil
+}
+
+// parseHexPalette parses 4 colours like E0F8D0 or #E0F8D0, separated by commas
+func parseHexPalette(list string) (TilePalette, error) {
+	var palette TilePalette
+
+	colours := strings.Split(list, ",")
+	if len(colours) != len(palette) {
+		return palette, fmt.Errorf("invalid palette %q, give 4 colours, lightest first", list)
+	}
+
+	for shade, c := range colours {
+		c = strings.TrimPrefix(strings.TrimSpace(c), "#")
+
+		v, err := strconv.ParseUint(c, 16, 32)
+		if err != nil || len(c) != 6 {
+			return palette, fmt.Errorf("invalid colour %q, use RRGGBB", c)
+		}
+
+		palette[shade] = color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xFF}
+	}
+
+	return palette, nil
+}
+
+// ShadeMap tells the shade (0 = lightest, 3 = darkest) of every colour of a screenshot
+type ShadeMap map[color.RGBA]byte
+
+// Shades returns the shade of every colour of the palette
+func (p TilePalette) Shades() ShadeMap {
+	shades := make(ShadeMap)
+	for shade, c := range p {
+		shades[c] = byte(shade)
+	}
+
+	return shades
+}
+
+// AutoShades finds the shades of a screenshot taken with an unknown palette, by
+// clustering its colours into 4 groups by luminance. It also returns the most
+// common colour of every shade. A screenshot with fewer than 4 colours gets
+// the lightest shades, the search tries every BGP anyway.
+func AutoShades(img image.Image) (ShadeMap, TilePalette, error) {
+	count := make(map[color.RGBA]int)
+
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			count[color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)]++
+		}
+	}
+
+	var colours []color.RGBA
+	for c := range count {
+		colours = append(colours, c)
+	}
+
+	if len(colours) == 0 {
+		return nil, TilePalette{}, errors.New("screenshot has no pixels")
+	}
+
+	// Lightest first
+	sort.Slice(colours, func(i, j int) bool {
+		if li, lj := luminance(colours[i]), luminance(colours[j]); li != lj {
+			return li > lj
+		}
+
+		return rgb(colours[i]) < rgb(colours[j])
+	})
+
+	shadeOf := clusterByLuminance(colours, count)
+
+	shades := make(ShadeMap)
+	var palette TilePalette
+	var best [4]int
+
+	for i, c := range colours {
+		shade := shadeOf[i]
+		shades[c] = shade
+
+		if count[c] > best[shade] {
+			palette[shade], best[shade] = c, count[c]
+		}
+	}
+
+	return shades, palette, nil
+}
+
+// luminanceLevels is how finely the colours are told apart when clustering them
+const luminanceLevels = 256
+
+// clusterByLuminance splits the colours, sorted lightest first, into 4 shades.
+// The pixels are counted per luminance level, and the levels are cut into the 4
+// groups with the least variance, which is exact in one dimension.
+func clusterByLuminance(colours []color.RGBA, count map[color.RGBA]int) []byte {
+	shadeOf := make([]byte, len(colours))
+
+	if len(colours) <= 4 {
+		for i := range colours {
+			shadeOf[i] = byte(i)
+		}
+
+		return shadeOf
+	}
+
+	level := func(c color.RGBA) int {
+		return luminance(c) * (luminanceLevels - 1) / luminance(color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF})
+	}
+
+	// Prefix sums of the weight, the luminance and its square, darkest level first
+	var weight, sum, squares [luminanceLevels + 1]float64
+	for _, c := range colours {
+		l, w := level(c), float64(count[c])
+		weight[l+1] += w
+		sum[l+1] += w * float64(l)
+		squares[l+1] += w * float64(l) * float64(l)
+	}
+
+	for l := 1; l <= luminanceLevels; l++ {
+		weight[l] += weight[l-1]
+		sum[l] += sum[l-1]
+		squares[l] += squares[l-1]
+	}
+
+	// cost is the variance of the levels from (included) to to (not included), times their weight
+	cost := func(from, to int) float64 {
+		w := weight[to] - weight[from]
+		if w == 0 {
+			return 0
+		}
+
+		s := sum[to] - sum[from]
+
+		return squares[to] - squares[from] - s*s/w
+	}
+
+	// best[k][l] is the cost of cutting the first l levels into k+1 groups,
+	// and cut[k][l] where the last of them starts
+	var best [4][luminanceLevels + 1]float64
+	var cut [4][luminanceLevels + 1]int
+	for l := 0; l <= luminanceLevels; l++ {
+		best[0][l] = cost(0, l)
+	}
+
+	for k := 1; k < 4; k++ {
+		for l := 0; l <= luminanceLevels; l++ {
+			best[k][l], cut[k][l] = best[k-1][l], l
+
+			for start := 0; start < l; start++ {
+				if c := best[k-1][start] + cost(start, l); c < best[k][l] {
+					best[k][l], cut[k][l] = c, start
+				}
+			}
+		}
+	}
+
+	// Walk the cuts back from the lightest group, which is shade 0
+	var starts [4]int
+	end := luminanceLevels
+	for k := 3; k >= 0; k-- {
+		if k > 0 {
+			starts[k] = cut[k][end]
+		}
+
+		end = starts[k]
+	}
+
+	for i, c := range colours {
+		group := 0
+		for k := range starts {
+			if level(c) >= starts[k] {
+				group = k
+			}
+		}
+
+		shadeOf[i] = byte(3 - group)
+	}
+
+	return shadeOf
+}
+
+// checkColor makes sure the image is 32-bit RGBA color, each R,G,B, A component requires 8-bits
+func checkColor(imData image.Image) error {
+	if imData.ColorModel() == color.RGBAModel {
+		return nil
+	}
+
+	var model string
+
+	switch imData.ColorModel() {
+	case color.GrayModel: // 8-bit grayscale
+		model = "Gray"
+	case color.NRGBAModel: // 32-bit non-alpha-premultiplied RGB color, each R,G,B component requires 8-bits
+		model = "NRGBA"
+	case color.NYCbCrAModel: // 32-bit non-alpha-premultiplied YCbCr color, each Y,Cb,Cr component requires 8-bits
+		model = "NYCbCrA"
+	case color.YCbCrModel: // 24-bit YCbCr color, each Y,Cb,Cr component requires 8-bits
+		model = "YCbCr"
+	default:
+		model = "Unknown"
+	}
+
+	return fmt.Errorf("not RGBA, color model is %s", model)
+}
@@ -0,0 +1,338 @@
+package gbgfx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// Patches describe the bytes a hack changed, so it can be shared without the ROM itself.
+// IPS is the oldest and most supported format, BPS also checks with CRC32s that it is
+// applied to the right ROM and that the result is right.
+
+const (
+	ipsMagic     = "PATCH"
+	ipsEOF       = "EOF"
+	ipsMaxOffset = 0xFFFFFF // offsets are 3 bytes
+	ipsMaxRecord = 0xFFFF   // sizes are 2 bytes
+
+	bpsMagic     = "BPS1"
+	bpsMaxTarget = 0x1000000 // 16 MB, twice the biggest Gameboy ROM
+)
+
+// BPS actions, in the low two bits of every command
+const (
+	bpsSourceRead = iota
+	bpsTargetRead
+	bpsSourceCopy
+	bpsTargetCopy
+)
+
+// MakeIPS makes an IPS patch that turns original into modified
+func MakeIPS(original, modified []byte) ([]byte, error) {
+	if len(modified) > ipsMaxOffset+1 {
+		return nil, errors.New("IPS patches can't address more than 16 MB")
+	}
+
+	patch := bytes.NewBufferString(ipsMagic)
+
+	for offset := 0; offset < len(modified); {
+		if offset < len(original) && original[offset] == modified[offset] {
+			offset++
+			continue
+		}
+
+		// "EOF" as an offset would end the patch, so start a byte earlier
+		start := offset
+		if start == 0x454F46 {
+			start--
+		}
+
+		end := offset
+		for end < len(modified) && end-start < ipsMaxRecord && (end >= len(original) || original[end] != modified[end]) {
+			end++
+		}
+
+		patch.Write([]byte{byte(start >> 16), byte(start >> 8), byte(start)})
+		patch.Write([]byte{byte((end - start) >> 8), byte(end - start)})
+		patch.Write(modified[start:end])
+
+		offset = end
+	}
+
+	patch.WriteString(ipsEOF)
+
+	// The truncate extension: the size of the result after EOF
+	if len(modified) < len(original) {
+		patch.Write([]byte{byte(len(modified) >> 16), byte(len(modified) >> 8), byte(len(modified))})
+	}
+
+	return patch.Bytes(), nil
+}
+
+// ApplyIPS applies an IPS patch to original, including run-length records and the truncate extension
+func ApplyIPS(original, patch []byte) ([]byte, error) {
+	if !bytes.HasPrefix(patch, []byte(ipsMagic)) {
+		return nil, errors.New("not an IPS patch")
+	}
+
+	result := append([]byte(nil), original...)
+
+	// write puts data at offset, growing the result if needed
+	write := func(offset int, data []byte) {
+		if end := offset + len(data); end > len(result) {
+			result = append(result, make([]byte, end-len(result))...)
+		}
+
+		copy(result[offset:], data)
+	}
+
+	p := len(ipsMagic)
+
+	for {
+		if p+3 > len(patch) {
+			return nil, errors.New("IPS patch ends without EOF")
+		}
+
+		if string(patch[p:p+3]) == ipsEOF {
+			p += 3
+			break
+		}
+
+		if p+5 > len(patch) {
+			return nil, errors.New("IPS patch is truncated")
+		}
+
+		offset := int(patch[p])<<16 | int(patch[p+1])<<8 | int(patch[p+2])
+		size := int(binary.BigEndian.Uint16(patch[p+3:]))
+		p += 5
+
+		if size > 0 {
+			if p+size > len(patch) {
+				return nil, errors.New("IPS patch is truncated")
+			}
+
+			write(offset, patch[p:p+size])
+			p += size
+
+			continue
+		}
+
+		// A record of size 0 is a run of the same byte
+		if p+3 > len(patch) {
+			return nil, errors.New("IPS patch is truncated")
+		}
+
+		size = int(binary.BigEndian.Uint16(patch[p:]))
+		write(offset, bytes.Repeat(patch[p+2:p+3], size))
+		p += 3
+	}
+
+	if p+3 <= len(patch) {
+		if size := int(patch[p])<<16 | int(patch[p+1])<<8 | int(patch[p+2]); size < len(result) {
+			result = result[:size]
+		}
+	}
+
+	return result, nil
+}
+
+// MakeBPS makes a BPS patch that turns source into target.
+// The patch copies the unchanged bytes from the source and stores the changed ones.
+func MakeBPS(source, target []byte) []byte {
+	var patch bytes.Buffer
+
+	patch.WriteString(bpsMagic)
+	writeBPSNumber(&patch, uint64(len(source)))
+	writeBPSNumber(&patch, uint64(len(target)))
+	writeBPSNumber(&patch, 0) // no metadata
+
+	for offset := 0; offset < len(target); {
+		same := func(i int) bool { return i < len(source) && source[i] == target[i] }
+
+		end := offset + 1
+		for end < len(target) && same(end) == same(offset) {
+			end++
+		}
+
+		action := uint64(bpsTargetRead)
+		if same(offset) {
+			action = bpsSourceRead
+		}
+
+		writeBPSNumber(&patch, uint64(end-offset-1)<<2|action)
+		if action == bpsTargetRead {
+			patch.Write(target[offset:end])
+		}
+
+		offset = end
+	}
+
+	_ = binary.Write(&patch, binary.LittleEndian, crc32.ChecksumIEEE(source))
+	_ = binary.Write(&patch, binary.LittleEndian, crc32.ChecksumIEEE(target))
+	_ = binary.Write(&patch, binary.LittleEndian, crc32.ChecksumIEEE(patch.Bytes()))
+
+	return patch.Bytes()
+}
+
+// ApplyBPS applies a BPS patch to source, after checking the CRC32 of the patch and the source,
+// and checks the CRC32 of the result
+func ApplyBPS(source, patch []byte) ([]byte, error) {
+	if !bytes.HasPrefix(patch, []byte(bpsMagic)) || len(patch) < len(bpsMagic)+12 {
+		return nil, errors.New("not a BPS patch")
+	}
+
+	footer := patch[len(patch)-12:]
+	sourceCRC := binary.LittleEndian.Uint32(footer)
+	targetCRC := binary.LittleEndian.Uint32(footer[4:])
+	patchCRC := binary.LittleEndian.Uint32(footer[8:])
+
+	if crc := crc32.ChecksumIEEE(patch[:len(patch)-4]); crc != patchCRC {
+		return nil, fmt.Errorf("BPS patch is corrupted: CRC32 %08X, expected %08X", crc, patchCRC)
+	}
+
+	if crc := crc32.ChecksumIEEE(source); crc != sourceCRC {
+		return nil, fmt.Errorf("wrong ROM for this patch: CRC32 %08X, expected %08X", crc, sourceCRC)
+	}
+
+	r := &bpsReader{data: patch[:len(patch)-12], p: len(bpsMagic)}
+
+	sourceSize := r.number()
+	targetSize := r.number()
+	metadataSize := r.number()
+
+	// The sizes are checked before they are used, a broken patch could give any number
+	if r.err != nil || metadataSize > uint64(len(r.data)-r.p) {
+		return nil, errors.New("BPS patch is truncated")
+	}
+
+	r.p += int(metadataSize)
+
+	if sourceSize != uint64(len(source)) {
+		return nil, fmt.Errorf("wrong ROM for this patch: %d bytes, expected %d", len(source), sourceSize)
+	}
+
+	if targetSize > bpsMaxTarget {
+		return nil, fmt.Errorf("BPS patch makes a ROM of %d bytes, more than %d MB", targetSize, bpsMaxTarget>>20)
+	}
+
+	target := make([]byte, 0, targetSize)
+	var sourceOffset, targetOffset int
+
+	for r.p < len(r.data) {
+		command := r.number()
+		if r.err == nil && command>>2 >= targetSize-uint64(len(target)) {
+			return nil, fmt.Errorf("BPS patch makes more than %d bytes", targetSize)
+		}
+
+		length := int(command>>2) + 1
+
+		switch command & 3 {
+		case bpsSourceRead:
+			if len(target)+length > len(source) {
+				return nil, errors.New("BPS patch reads past the end of the ROM")
+			}
+
+			target = append(target, source[len(target):len(target)+length]...)
+		case bpsTargetRead:
+			if r.p+length > len(r.data) {
+				return nil, errors.New("BPS patch is truncated")
+			}
+
+			target = append(target, r.data[r.p:r.p+length]...)
+			r.p += length
+		case bpsSourceCopy:
+			sourceOffset += r.signedNumber()
+			if sourceOffset < 0 || sourceOffset+length > len(source) {
+				return nil, errors.New("BPS patch copies from outside the ROM")
+			}
+
+			target = append(target, source[sourceOffset:sourceOffset+length]...)
+			sourceOffset += length
+		case bpsTargetCopy:
+			targetOffset += r.signedNumber()
+			if targetOffset < 0 || targetOffset >= len(target) {
+				return nil, errors.New("BPS patch copies from outside the result")
+			}
+
+			// The copy may overlap what it writes, to repeat a pattern, so it goes byte by byte
+			for i := 0; i < length; i++ {
+				target = append(target, target[targetOffset])
+				targetOffset++
+			}
+		}
+
+		if r.err != nil {
+			return nil, errors.New("BPS patch is truncated")
+		}
+	}
+
+	if uint64(len(target)) != targetSize {
+		return nil, fmt.Errorf("BPS patch made %d bytes, expected %d", len(target), targetSize)
+	}
+
+	if crc := crc32.ChecksumIEEE(target); crc != targetCRC {
+		return nil, fmt.Errorf("patched ROM is wrong: CRC32 %08X, expected %08X", crc, targetCRC)
+	}
+
+	return target, nil
+}
+
+// writeBPSNumber writes a number in the variable length encoding of BPS
+func writeBPSNumber(w *bytes.Buffer, n uint64) {
+	for {
+		x := byte(n & 0x7F)
+		n >>= 7
+
+		if n == 0 {
+			w.WriteByte(0x80 | x)
+			return
+		}
+
+		w.WriteByte(x)
+		n--
+	}
+}
+
+// bpsReader reads the variable length numbers of a BPS patch
+type bpsReader struct {
+	data []byte
+	p    int
+	err  error
+}
+
+// number reads an unsigned number
+func (r *bpsReader) number() uint64 {
+	var n uint64
+	shift := uint64(1)
+
+	for {
+		if r.p >= len(r.data) {
+			r.err = errors.New("unexpected end of patch")
+			return 0
+		}
+
+		x := r.data[r.p]
+		r.p++
+
+		n += uint64(x&0x7F) * shift
+		if x&0x80 != 0 {
+			return n
+		}
+
+		shift <<= 7
+		n += shift
+	}
+}
+
+// signedNumber reads a number whose lowest bit is the sign
+func (r *bpsReader) signedNumber() int {
+	n := r.number()
+	if n&1 != 0 {
+		return -int(n >> 1)
+	}
+
+	return int(n >> 1)
+}
@@ -0,0 +1,233 @@
+package gbgfx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"math/rand"
+	"testing"
+)
+
+// patchCase is an original ROM and the modified one a patch should turn it into
+type patchCase struct {
+	name     string
+	original []byte
+	modified []byte
+}
+
+func patchCases() []patchCase {
+	rnd := rand.New(rand.NewSource(1))
+	random := func(n int) []byte {
+		b := make([]byte, n)
+		rnd.Read(b)
+
+		return b
+	}
+
+	rom := random(0x8000)
+	with := func(change func(b []byte) []byte) []byte {
+		return change(append([]byte(nil), rom...))
+	}
+
+	// A change at 0x454F46, the offset that reads as "EOF" in an IPS patch
+	big := random(0x460000)
+
+	return []patchCase{
+		{"identical", rom, with(func(b []byte) []byte { return b })},
+		{"first byte", rom, with(func(b []byte) []byte { b[0]++; return b })},
+		{"last byte", rom, with(func(b []byte) []byte { b[len(b)-1]++; return b })},
+		{"tile", rom, with(func(b []byte) []byte { copy(b[0x1230:], random(TileSize)); return b })},
+		{"longer than a record", rom, with(func(b []byte) []byte { copy(b[0x10:], random(0x10010)[:len(b)-0x10]); return b })},
+		{"grown", rom, with(func(b []byte) []byte { return append(b, random(0x4000)...) })},
+		{"truncated", rom, with(func(b []byte) []byte { return b[:0x4000] })},
+		{"EOF offset", big, func() []byte {
+			b := append([]byte(nil), big...)
+			b[0x454F46] ^= 0xFF
+			b[0x454F48] ^= 0xFF
+
+			return b
+		}()},
+	}
+}
+
+func TestIPSRoundTrip(t *testing.T) {
+	for _, c := range patchCases() {
+		patch, err := MakeIPS(c.original, c.modified)
+		if err != nil {
+			t.Errorf("%s: MakeIPS: %v", c.name, err)
+			continue
+		}
+
+		result, err := ApplyIPS(c.original, patch)
+		if err != nil {
+			t.Errorf("%s: ApplyIPS: %v", c.name, err)
+			continue
+		}
+
+		if !bytes.Equal(result, c.modified) {
+			t.Errorf("%s: the patched ROM is %d bytes and differs from the modified one (%d bytes)", c.name, len(result), len(c.modified))
+		}
+	}
+}
+
+func TestApplyIPS(t *testing.T) {
+	original := []byte{0, 1, 2, 3, 4, 5, 6, 7}
+
+	tests := []struct {
+		name  string
+		patch string
+		want  []byte
+	}{
+		{"no records", "PATCHEOF", original},
+		{"record", "PATCH\x00\x00\x02\x00\x02\xAA\xBBEOF", []byte{0, 1, 0xAA, 0xBB, 4, 5, 6, 7}},
+		{"run", "PATCH\x00\x00\x01\x00\x00\x00\x03\xCCEOF", []byte{0, 0xCC, 0xCC, 0xCC, 4, 5, 6, 7}},
+		{"past the end", "PATCH\x00\x00\x09\x00\x01\xDDEOF", []byte{0, 1, 2, 3, 4, 5, 6, 7, 0, 0xDD}},
+		{"truncate", "PATCHEOF\x00\x00\x03", []byte{0, 1, 2}},
+	}
+
+	for _, test := range tests {
+		result, err := ApplyIPS(original, []byte(test.patch))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if !bytes.Equal(result, test.want) {
+			t.Errorf("%s: got % X, want % X", test.name, result, test.want)
+		}
+	}
+}
+
+func TestApplyIPSErrors(t *testing.T) {
+	for _, patch := range []string{
+		"",
+		"PATCX",
+		"PATCH",
+		"PATCH\x00\x00\x01\x00",
+		"PATCH\x00\x00\x01\x00\x04\xAA\xBB",
+		"PATCH\x00\x00\x01\x00\x00\x00",
+		"PATCH\x00\x00\x01\x00\x01\xAA",
+	} {
+		if _, err := ApplyIPS(make([]byte, 8), []byte(patch)); err == nil {
+			t.Errorf("%q: no error", patch)
+		}
+	}
+}
+
+func TestMakeIPSTooBig(t *testing.T) {
+	if _, err := MakeIPS(nil, make([]byte, ipsMaxOffset+2)); err == nil {
+		t.Error("no error for a ROM bigger than 16 MB")
+	}
+}
+
+func TestBPSRoundTrip(t *testing.T) {
+	for _, c := range patchCases() {
+		result, err := ApplyBPS(c.original, MakeBPS(c.original, c.modified))
+		if err != nil {
+			t.Errorf("%s: ApplyBPS: %v", c.name, err)
+			continue
+		}
+
+		if !bytes.Equal(result, c.modified) {
+			t.Errorf("%s: the patched ROM is %d bytes and differs from the modified one (%d bytes)", c.name, len(result), len(c.modified))
+		}
+	}
+}
+
+// bpsPatch makes a BPS patch out of its header numbers and commands, with the
+// right CRC32s, so the commands are checked and not the CRC32s
+func bpsPatch(source, target []byte, sourceSize, targetSize, metadataSize uint64, commands []byte) []byte {
+	var patch bytes.Buffer
+
+	patch.WriteString(bpsMagic)
+	writeBPSNumber(&patch, sourceSize)
+	writeBPSNumber(&patch, targetSize)
+	writeBPSNumber(&patch, metadataSize)
+	patch.Write(commands)
+
+	_ = binary.Write(&patch, binary.LittleEndian, crc32.ChecksumIEEE(source))
+	_ = binary.Write(&patch, binary.LittleEndian, crc32.ChecksumIEEE(target))
+	_ = binary.Write(&patch, binary.LittleEndian, crc32.ChecksumIEEE(patch.Bytes()))
+
+	return patch.Bytes()
+}
+
+// bpsCommand encodes a command and its operands
+func bpsCommand(action, length uint64, operands ...uint64) []byte {
+	var b bytes.Buffer
+
+	writeBPSNumber(&b, (length-1)<<2|action)
+	for _, n := range operands {
+		writeBPSNumber(&b, n)
+	}
+
+	return b.Bytes()
+}
+
+func TestApplyBPSCopies(t *testing.T) {
+	source := []byte("ABCDEFGH")
+	target := []byte("EFGABABABA")
+
+	var commands []byte
+	commands = append(commands, bpsCommand(bpsSourceCopy, 3, 4<<1)...)   // EFG from source offset 4
+	commands = append(commands, bpsCommand(bpsSourceCopy, 2, 7<<1|1)...) // AB, from 7 back to 0
+	commands = append(commands, bpsCommand(bpsTargetCopy, 5, 3<<1)...)   // ABABA, overlapping itself
+	patch := bpsPatch(source, target, uint64(len(source)), uint64(len(target)), 0, commands)
+
+	result, err := ApplyBPS(source, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(result, target) {
+		t.Errorf("got %q, want %q", result, target)
+	}
+}
+
+func TestApplyBPSErrors(t *testing.T) {
+	source := []byte("ABCDEFGH")
+	target := []byte("ABCD")
+	read := bpsCommand(bpsSourceRead, 4)
+
+	corrupted := MakeBPS(source, target)
+	corrupted[len(bpsMagic)+3] ^= 0xFF
+
+	tests := []struct {
+		name  string
+		patch []byte
+	}{
+		{"empty", nil},
+		{"not BPS", []byte("PATCHEOF")},
+		{"corrupted", corrupted},
+		{"wrong ROM", MakeBPS([]byte("ABCDEFGX"), target)},
+		{"wrong source size", bpsPatch(source, target, 7, 4, 0, read)},
+		{"huge target size", bpsPatch(source, target, 8, 1<<62, 0, read)},
+		{"huge metadata size", bpsPatch(source, target, 8, 4, 1<<62, read)},
+		{"metadata past the end", bpsPatch(source, target, 8, 4, 3, read)},
+		{"more than the target size", bpsPatch(source, target, 8, 4, 0, bpsCommand(bpsSourceRead, 5))},
+		{"huge length", bpsPatch(source, target, 8, 4, 0, bpsCommand(bpsTargetCopy, 1<<62, 0))},
+		{"less than the target size", bpsPatch(source, target, 8, 4, 0, bpsCommand(bpsSourceRead, 3))},
+		{"target read past the end", bpsPatch(source, target, 8, 4, 0, bpsCommand(bpsTargetRead, 4))},
+		{"source copy outside", bpsPatch(source, target, 8, 4, 0, bpsCommand(bpsSourceCopy, 4, 6<<1))},
+		{"target copy outside", bpsPatch(source, target, 8, 4, 0, bpsCommand(bpsTargetCopy, 4, 0))},
+		{"wrong result", bpsPatch(source, []byte("ABCE"), 8, 4, 0, read)},
+	}
+
+	for _, test := range tests {
+		if _, err := ApplyBPS(source, test.patch); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+}
+
+func TestBPSNumber(t *testing.T) {
+	for _, n := range []uint64{0, 1, 0x7F, 0x80, 0x407F, 0x4080, 1 << 32, 1<<62 + 5} {
+		var b bytes.Buffer
+		writeBPSNumber(&b, n)
+
+		r := &bpsReader{data: b.Bytes()}
+		if got := r.number(); got != n || r.err != nil || r.p != b.Len() {
+			t.Errorf("%d: read back %d, %d of %d bytes, %v", n, got, r.p, b.Len(), r.err)
+		}
+	}
+}
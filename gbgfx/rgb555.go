@@ -0,0 +1,119 @@
+package gbgfx
+
+import (
+	"encoding/binary"
+	"image/color"
+	"sort"
+)
+
+// minKnownColours is how many colours of a palette have to be known to search for it,
+// a single colour matches all over the ROM
+const minKnownColours = 2
+
+// PaletteMatch is a location in the ROM where a palette was found
+type PaletteMatch struct {
+	Offset   int // offset in the ROM file of the first colour
+	Palette  int // number of the palette
+	Distance int // sum of the differences of the colour channels, 0 for an exact match
+}
+
+// To555 converts an 8-bit colour channel to the 5 bits of the CGB
+func To555(c uint8) uint16 {
+	return (uint16(c)*31 + 127) / 255
+}
+
+// RGB555 converts a colour to the 15-bit format of the CGB: red in bits 0-4,
+// green in bits 5-9 and blue in bits 10-14, stored little endian
+func RGB555(c color.RGBA) uint16 {
+	return To555(c.R) | To555(c.G)<<5 | To555(c.B)<<10
+}
+
+// RGB555 converts the colours of the palette to the format of the CGB
+func (p CGBPalette) RGB555() [colorsPerTile]uint16 {
+	var words [colorsPerTile]uint16
+	for i, c := range p.Colours {
+		words[i] = RGB555(c)
+	}
+
+	return words
+}
+
+// distance555 returns the sum of the differences of the channels of two CGB colours
+func distance555(a, b uint16) int {
+	distance := 0
+
+	for shift := 0; shift < 15; shift += 5 {
+		d := int(a>>shift&0x1F) - int(b>>shift&0x1F)
+		if d < 0 {
+			d = -d
+		}
+
+		distance += d
+	}
+
+	return distance
+}
+
+// FindPalettes searches the ROM for the palettes, stored the way the game writes
+// them to the CGB palette RAM: 4 colours of 2 bytes. The colours of a screenshot
+// were rounded, or even corrected, by the emulator, so every channel may be off
+// by up to tolerance out of 31. The colours that aren't known match anything, and
+// the palettes with fewer than 2 known colours aren't searched. The best matches
+// come first.
+func (r *ROM) FindPalettes(palettes []CGBPalette, tolerance int) []PaletteMatch {
+	var matches []PaletteMatch
+
+	for n, palette := range palettes {
+		known := 0
+		for _, k := range palette.Known {
+			if k {
+				known++
+			}
+		}
+
+		if known < minKnownColours {
+			continue
+		}
+
+		words := palette.RGB555()
+
+		for offset := 0; offset+2*colorsPerTile <= len(r.Data); offset++ {
+			distance, ok := 0, true
+
+			for i, want := range words {
+				if !palette.Known[i] {
+					continue
+				}
+
+				// Bit 15 isn't a colour, so games may leave anything there
+				word := binary.LittleEndian.Uint16(r.Data[offset+2*i:]) & 0x7FFF
+				if !withinTolerance(word, want, tolerance) {
+					ok = false
+					break
+				}
+
+				distance += distance555(word, want)
+			}
+
+			if ok {
+				matches = append(matches, PaletteMatch{Offset: offset, Palette: n, Distance: distance})
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Distance < matches[j].Distance })
+
+	return matches
+}
+
+// withinTolerance tells if every channel of the two colours differs by tolerance at most
+func withinTolerance(a, b uint16, tolerance int) bool {
+	for shift := 0; shift < 15; shift += 5 {
+		d := int(a>>shift&0x1F) - int(b>>shift&0x1F)
+		if d < -tolerance || d > tolerance {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,82 @@
+package gbgfx
+
+import (
+	"encoding/binary"
+	"image/color"
+	"testing"
+)
+
+func TestRGB555(t *testing.T) {
+	for c, want := range map[uint8]uint16{0x00: 0, 0x08: 1, 0x80: 16, 0xF8: 30, 0xFF: 31} {
+		if got := To555(c); got != want {
+			t.Errorf("$%02X: %d, want %d", c, got, want)
+		}
+	}
+
+	tests := []struct {
+		colour color.RGBA
+		want   uint16
+	}{
+		{color.RGBA{R: 0xFF, A: 0xFF}, 0x001F},
+		{color.RGBA{G: 0xFF, A: 0xFF}, 0x03E0},
+		{color.RGBA{B: 0xFF, A: 0xFF}, 0x7C00},
+		{color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}, 0x7FFF},
+	}
+
+	for _, test := range tests {
+		if got := RGB555(test.colour); got != test.want {
+			t.Errorf("%v: $%04X, want $%04X", test.colour, got, test.want)
+		}
+
+		// Converting back gives the same colour, the channels are 0 or 31
+		if got := from555(test.want); got != test.colour {
+			t.Errorf("$%04X: %v, want %v", test.want, got, test.colour)
+		}
+	}
+}
+
+func TestFindPalettes(t *testing.T) {
+	palette := CGBPalette{
+		Colours: [colorsPerTile]color.RGBA{{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}, {R: 0x80, G: 0x80, A: 0xFF}, {}, {A: 0xFF}},
+		Known:   [colorsPerTile]bool{true, true, false, true},
+	}
+
+	words := palette.RGB555()
+	rom := &ROM{Data: make([]byte, 0x8000)}
+
+	put := func(offset int, colours ...uint16) {
+		for i, c := range colours {
+			binary.LittleEndian.PutUint16(rom.Data[offset+2*i:], c)
+		}
+	}
+
+	put(0x5000, words[0]|0x8000, words[1], 0x1234, words[3]) // bit 15 set, and anything for the unknown colour
+	put(0x6000, words[0], words[1]+1, 0, words[3]+2)         // the second and the last colour off by 1 and 2
+
+	for _, test := range []struct {
+		tolerance int
+		want      []PaletteMatch
+	}{
+		{0, []PaletteMatch{{Offset: 0x5000}}},
+		{1, []PaletteMatch{{Offset: 0x5000}}},
+		{2, []PaletteMatch{{Offset: 0x5000}, {Offset: 0x6000, Distance: 3}}},
+	} {
+		got := rom.FindPalettes([]CGBPalette{palette}, test.tolerance)
+		if len(got) != len(test.want) {
+			t.Errorf("tolerance %d: %+v, want %+v", test.tolerance, got, test.want)
+			continue
+		}
+
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("tolerance %d: %+v, want %+v", test.tolerance, got, test.want)
+			}
+		}
+	}
+
+	// A single known colour matches all over the ROM, so it isn't searched
+	single := CGBPalette{Known: [colorsPerTile]bool{true}}
+	if got := rom.FindPalettes([]CGBPalette{single}, 0); len(got) != 0 {
+		t.Errorf("%d matches of a palette of a single colour", len(got))
+	}
+}
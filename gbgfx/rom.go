@@ -0,0 +1,111 @@
+package gbgfx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ROM is the content of a Gameboy cartridge
+type ROM struct {
+	Data  []byte
+	index *Index
+}
+
+// ReadROM reads a ROM file from the disk
+func ReadROM(path string) (*ROM, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, errors.New(path + " is empty")
+	}
+
+	return &ROM{Data: data}, nil
+}
+
+// Save writes the ROM to the disk
+func (r *ROM) Save(path string) error {
+	return os.WriteFile(path, r.Data, 0o644)
+}
+
+// WriteTile replaces the tile at offset, and tells if any byte changed.
+// The index isn't updated, so search before writing.
+func (r *ROM) WriteTile(offset int, t Tile) (bool, error) {
+	if offset < 0 || offset+TileSize > len(r.Data) {
+		return false, fmt.Errorf("offset 0x%X is out of the ROM", offset)
+	}
+
+	if bytes.Equal(r.Data[offset:offset+TileSize], t[:]) {
+		return false, nil
+	}
+
+	copy(r.Data[offset:], t[:])
+
+	return true, nil
+}
+
+// FixChecksums recomputes the header and the global checksum after the ROM was modified.
+// The boot ROM refuses to start a cartridge with a wrong header checksum.
+func (r *ROM) FixChecksums() error {
+	if len(r.Data) < headerEnd {
+		return errors.New("ROM is too short to have a cartridge header")
+	}
+
+	r.Data[headerChecksumOffset] = HeaderChecksum(r.Data)
+
+	// The global checksum covers the header checksum, so it goes second
+	global := GlobalChecksum(r.Data)
+	r.Data[globalChecksumOffset], r.Data[globalChecksumOffset+1] = byte(global>>8), byte(global)
+
+	return nil
+}
+
+// Header parses the cartridge header of the ROM
+func (r *ROM) Header() (Header, error) {
+	return ParseHeader(r.Data)
+}
+
+// LoadIndex indexes the ROM for searching, using the cache in cacheDir (see LoadIndex).
+// Without it, the ROM is indexed (and not cached) on the first search.
+// The error only tells that the index couldn't be cached, the ROM can be searched anyway.
+func (r *ROM) LoadIndex(cacheDir string) error {
+	index, err := LoadIndex(cacheDir, r.Data)
+	r.index = index
+
+	return err
+}
+
+// FindTiles searches the ROM for the tiles, which hold the shades of a screenshot.
+// Every tile is also searched flipped and with every palette of opts.BGPs.
+func (r *ROM) FindTiles(tiles []Tile, opts SearchOptions) []Match {
+	if r.index == nil {
+		r.index = NewIndex(r.Data)
+	}
+
+	bgps := opts.BGPs
+	if len(bgps) == 0 {
+		bgps = AllBGPs()
+	}
+
+	return findTileAddresses(tiles, r.index, bgps, opts.AllOccurrences)
+}
+
+// FindScreenshot searches the ROM for the tiles of the screenshot, when the
+// background grid of the screenshot starts at align
+func (r *ROM) FindScreenshot(s *Screenshot, align Alignment, opts SearchOptions) ([]Match, error) {
+	tiles, err := s.UniqueTiles(align)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := r.FindTiles(tiles, opts)
+	for i := range matches {
+		matches[i].Align = align
+	}
+
+	return matches, nil
+}
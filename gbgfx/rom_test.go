@@ -0,0 +1,28 @@
+package gbgfx
+
+import "testing"
+
+func TestFixChecksums(t *testing.T) {
+	rom := &ROM{Data: make([]byte, 0x8000)}
+	for i := range rom.Data {
+		rom.Data[i] = byte(i * 7)
+	}
+
+	if err := rom.FixChecksums(); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := rom.Header()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h.HeaderChecksum != h.ComputedHeaderChecksum || h.GlobalChecksum != h.ComputedGlobalChecksum {
+		t.Errorf("header $%02X, computed $%02X, global $%04X, computed $%04X",
+			h.HeaderChecksum, h.ComputedHeaderChecksum, h.GlobalChecksum, h.ComputedGlobalChecksum)
+	}
+
+	if err := (&ROM{Data: make([]byte, headerEnd-1)}).FixChecksums(); err == nil {
+		t.Error("no error for a ROM without a header")
+	}
+}
@@ -0,0 +1,355 @@
+package gbgfx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+)
+
+// BESS (Best Effort Save State) is the save state format of SameBoy, which other
+// emulators can read and write too. The emulator's own data comes first, and the
+// BESS blocks are appended to it, found from a footer at the end of the file:
+// the offset of the first block (4 bytes, little endian) and "BESS".
+const (
+	bessMagic      = "BESS"
+	bessFooterSize = 8
+	bessBlockHead  = 8 // name (4 bytes) and length (4 bytes, little endian)
+	bessCoreSize   = 0xD0
+	bessVersion    = 1
+
+	// Offsets in the CORE block
+	bessCoreModel   = 0x04
+	bessCoreIO      = 0x18 // $FF00-$FF7F
+	bessCoreVRAM    = 0xA0 // size and file offset, 4 bytes each
+	bessCoreOAM     = 0xB0
+	bessCoreBGPals  = 0xC0
+	bessCoreOBJPals = 0xC8
+)
+
+// Offsets of the video registers in the I/O registers ($FF00-$FF7F)
+const (
+	regLCDC = 0x40
+	regSCY  = 0x42
+	regSCX  = 0x43
+	regBGP  = 0x47
+	regOBP0 = 0x48
+	regOBP1 = 0x49
+	regWY   = 0x4A
+	regWX   = 0x4B
+)
+
+// LCDC bits
+const (
+	lcdcBGEnable  = 1 << 0 // on the CGB, the BG and window lose their priority instead
+	lcdcOBJEnable = 1 << 1
+	lcdcOBJSize   = 1 << 2 // 8x16 sprites
+	lcdcBGMap     = 1 << 3 // $9C00 instead of $9800
+	lcdcTileData  = 1 << 4 // $8000 unsigned instead of $8800 signed
+	lcdcWindow    = 1 << 5
+	lcdcWindowMap = 1 << 6
+)
+
+// The BG maps, the sprites and the CGB attributes
+const (
+	bgMapLow        = 0x1800 // $9800 in VRAM
+	bgMapHigh       = 0x1C00 // $9C00 in VRAM
+	bgMapTiles      = 32
+	oamEntries      = 40
+	oamEntrySize    = 4
+	cgbPaletteSize  = 8 // 4 colours of 2 bytes
+	attrCGBPalettes = 0x07
+	attrBank        = 1 << 3
+	attrFlipX       = 1 << 5
+	attrFlipY       = 1 << 6
+)
+
+// SaveState is the state of the video hardware, read from an emulator save state
+type SaveState struct {
+	Model       string // model in the notation of BESS, e.g. "GD  " for a DMG or "CC  " for a CGB
+	VRAM        *VRAM
+	OAM         []byte
+	BGPalettes  []byte // CGB palette RAM, 8 palettes of 4 colours in RGB555
+	OBJPalettes []byte
+	LCDC        byte
+	SCX         byte
+	SCY         byte
+	WX          byte
+	WY          byte
+	BGP         byte
+	OBP0        byte
+	OBP1        byte
+}
+
+// ReadSaveState reads an emulator save state from the disk (see ParseSaveState)
+func ReadSaveState(path string) (*SaveState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := ParseSaveState(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// ParseSaveState reads the video state out of a BESS save state (SameBoy, and the
+// emulators that export it), an mGBA one, raw or in a PNG, or a BGB one (.sna).
+func ParseSaveState(data []byte) (*SaveState, error) {
+	switch {
+	case len(data) >= bessFooterSize && string(data[len(data)-len(bessMagic):]) == bessMagic:
+		return parseBESS(data)
+	case bytes.HasPrefix(data, []byte(pngSignature)):
+		state, err := mgbaPNGState(data)
+		if err != nil {
+			return nil, err
+		}
+
+		return parseMGBA(state)
+	case isMGBA(data):
+		return parseMGBA(data)
+	}
+
+	if blocks, ok := bgbBlocks(data); ok {
+		return parseBGB(blocks)
+	}
+
+	return nil, errors.New("not a BESS, mGBA or BGB save state")
+}
+
+// parseBESS walks the BESS blocks up to END and reads the CORE block
+func parseBESS(data []byte) (*SaveState, error) {
+	offset := int(binary.LittleEndian.Uint32(data[len(data)-bessFooterSize:]))
+	end := len(data) - bessFooterSize
+
+	for offset+bessBlockHead <= end {
+		name := string(data[offset : offset+4])
+		length := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		offset += bessBlockHead
+
+		if length < 0 || offset+length > end {
+			return nil, fmt.Errorf("BESS block %q is cut short", name)
+		}
+
+		switch name {
+		case "CORE":
+			return parseBESSCore(data, data[offset:offset+length])
+		case "END ":
+			return nil, errors.New("BESS save state without a CORE block")
+		}
+
+		offset += length
+	}
+
+	return nil, errors.New("BESS save state without a CORE block")
+}
+
+// parseBESSCore reads the model, the video registers and the buffers of the CORE block.
+// The buffers are given by their size and their offset in the file.
+func parseBESSCore(data, core []byte) (*SaveState, error) {
+	if len(core) < bessCoreSize {
+		return nil, fmt.Errorf("BESS CORE block is %d bytes, expected %d", len(core), bessCoreSize)
+	}
+
+	if major := binary.LittleEndian.Uint16(core); major != bessVersion {
+		return nil, fmt.Errorf("BESS version %d isn't supported", major)
+	}
+
+	buffer := func(at int) ([]byte, error) {
+		size := int(binary.LittleEndian.Uint32(core[at:]))
+		offset := int(binary.LittleEndian.Uint32(core[at+4:]))
+
+		if offset+size > len(data) {
+			return nil, fmt.Errorf("BESS buffer of %d bytes at 0x%X is out of the file", size, offset)
+		}
+
+		return data[offset : offset+size], nil
+	}
+
+	io := core[bessCoreIO:]
+	s := &SaveState{
+		Model: string(core[bessCoreModel : bessCoreModel+4]),
+		LCDC:  io[regLCDC],
+		SCX:   io[regSCX],
+		SCY:   io[regSCY],
+		WX:    io[regWX],
+		WY:    io[regWY],
+		BGP:   io[regBGP],
+		OBP0:  io[regOBP0],
+		OBP1:  io[regOBP1],
+	}
+
+	vram, err := buffer(bessCoreVRAM)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(vram) != VRAMBankSize && len(vram) != 2*VRAMBankSize {
+		return nil, fmt.Errorf("BESS VRAM is %d bytes, expected 8 KB or 16 KB", len(vram))
+	}
+
+	if s.VRAM, err = ParseVRAMDump(vram); err != nil {
+		return nil, err
+	}
+
+	if s.OAM, err = buffer(bessCoreOAM); err != nil {
+		return nil, err
+	}
+
+	if s.BGPalettes, err = buffer(bessCoreBGPals); err != nil {
+		return nil, err
+	}
+
+	if s.OBJPalettes, err = buffer(bessCoreOBJPals); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// CGB tells if the save state is of a Gameboy Color
+func (s *SaveState) CGB() bool {
+	return len(s.Model) > 0 && s.Model[0] == 'C' && len(s.VRAM.Banks) == 2
+}
+
+// Alignment is where the first whole background tile starts on the screen, from SCX and SCY
+func (s *SaveState) Alignment() Alignment {
+	return Alignment{X: (8 - int(s.SCX)%8) % 8, Y: (8 - int(s.SCY)%8) % 8}
+}
+
+// Window returns the part of the screen the window covers, empty if it's off
+func (s *SaveState) Window() image.Rectangle {
+	if s.LCDC&lcdcWindow == 0 || (!s.CGB() && s.LCDC&lcdcBGEnable == 0) {
+		return image.Rectangle{}
+	}
+
+	return image.Rect(int(s.WX)-7, int(s.WY), gbScreenXRes, gbScreenYRes).Intersect(image.Rect(0, 0, gbScreenXRes, gbScreenYRes))
+}
+
+// Screen draws the background and the window the way the Gameboy shows them,
+// without the sprites. On the DMG the colour numbers of the tiles are drawn in the
+// greys of TileImage, as with the identity BGP: BGP may show two colours in the
+// same shade (e.g. during a fade), which couldn't be told apart anymore. On the
+// CGB the colours of the palette RAM are used.
+func (s *SaveState) Screen() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, gbScreenXRes, gbScreenYRes))
+	window := s.Window()
+
+	for y := 0; y < gbScreenYRes; y++ {
+		for x := 0; x < gbScreenXRes; x++ {
+			// Position in the 256x256 background map, and which map
+			mapX, mapY, base := (x+int(s.SCX))%bgMapRes, (y+int(s.SCY))%bgMapRes, bgMapLow
+			if s.LCDC&lcdcBGMap != 0 {
+				base = bgMapHigh
+			}
+
+			if image.Pt(x, y).In(window) {
+				mapX, mapY, base = x-window.Min.X, y-window.Min.Y, bgMapLow
+				if s.LCDC&lcdcWindowMap != 0 {
+					base = bgMapHigh
+				}
+			}
+
+			img.SetRGBA(x, y, s.bgPixel(base+(mapY/8)*bgMapTiles+mapX/8, mapX%8, mapY%8))
+		}
+	}
+
+	return img
+}
+
+// bgPixel returns the colour of the pixel (x,y) of the tile at index in the BG maps
+func (s *SaveState) bgPixel(index, x, y int) color.RGBA {
+	if !s.CGB() && s.LCDC&lcdcBGEnable == 0 {
+		return GreyPalette[0]
+	}
+
+	bank, attr := s.VRAM.Banks[0], byte(0)
+	if s.CGB() {
+		attr = s.VRAM.Banks[1][index]
+		if attr&attrBank != 0 {
+			bank = s.VRAM.Banks[1]
+		}
+	}
+
+	number := int(s.VRAM.Banks[0][index])
+	address := number * TileSize
+	if s.LCDC&lcdcTileData == 0 {
+		address = 0x1000 + int(int8(number))*TileSize
+	}
+
+	if attr&attrFlipX != 0 {
+		x = 7 - x
+	}
+
+	if attr&attrFlipY != 0 {
+		y = 7 - y
+	}
+
+	bit := uint(7 - x)
+	c := bank[address+2*y]>>bit&1 | bank[address+2*y+1]>>bit&1<<1
+
+	if !s.CGB() {
+		return GreyPalette[c]
+	}
+
+	offset := int(attr&attrCGBPalettes)*cgbPaletteSize + int(c)*2
+	if offset+2 > len(s.BGPalettes) {
+		return GreyPalette[c]
+	}
+
+	return from555(binary.LittleEndian.Uint16(s.BGPalettes[offset:]))
+}
+
+// from555 converts a colour of the CGB palette RAM to 8 bits per channel
+func from555(v uint16) color.RGBA {
+	channel := func(shift uint) uint8 {
+		c := uint8(v >> shift & 0x1F)
+		return c<<3 | c>>2
+	}
+
+	return color.RGBA{R: channel(0), G: channel(5), B: channel(10), A: 0xFF}
+}
+
+// SpriteTiles returns the tiles of the sprites on the screen, without the blank
+// ones and the duplicates (see VRAM.Tiles)
+func (s *SaveState) SpriteTiles() []VRAMTile {
+	if s.LCDC&lcdcOBJEnable == 0 {
+		return nil
+	}
+
+	height := 8
+	if s.LCDC&lcdcOBJSize != 0 {
+		height = 16
+	}
+
+	var tiles []VRAMTile
+
+	for i := 0; i+oamEntrySize <= len(s.OAM) && i < oamEntries*oamEntrySize; i += oamEntrySize {
+		y, x, number, attr := int(s.OAM[i])-16, int(s.OAM[i+1])-8, int(s.OAM[i+2]), s.OAM[i+3]
+		if y <= -height || y >= gbScreenYRes || x <= -8 || x >= gbScreenXRes {
+			continue
+		}
+
+		bank := 0
+		if s.CGB() && attr&attrBank != 0 {
+			bank = 1
+		}
+
+		numbers := []int{number}
+		if height == 16 {
+			numbers = []int{number &^ 1, number | 1}
+		}
+
+		for _, n := range numbers {
+			tiles = append(tiles, s.VRAM.tile(bank, n*TileSize))
+		}
+	}
+
+	return uniqueVRAMTiles(tiles)
+}
@@ -0,0 +1,324 @@
+package gbgfx
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+// bessState is what goes into a test BESS save state
+type bessState struct {
+	model   string
+	version uint16
+	io      map[int]byte
+	vram    []byte
+}
+
+// bess makes a BESS save state: some bytes of the emulator first, then the
+// buffers the CORE block points to, the blocks and the footer
+func (s bessState) bess() []byte {
+	data := []byte(strings.Repeat("emulator", 16))
+
+	buffer := func(core []byte, at int, b []byte) {
+		binary.LittleEndian.PutUint32(core[at:], uint32(len(b)))
+		binary.LittleEndian.PutUint32(core[at+4:], uint32(len(data)))
+		data = append(data, b...)
+	}
+
+	core := make([]byte, bessCoreSize)
+	binary.LittleEndian.PutUint16(core, s.version)
+	copy(core[bessCoreModel:], s.model)
+
+	for register, v := range s.io {
+		core[bessCoreIO+register] = v
+	}
+
+	buffer(core, bessCoreVRAM, s.vram)
+	buffer(core, bessCoreOAM, make([]byte, oamEntries*oamEntrySize))
+	buffer(core, bessCoreBGPals, make([]byte, 8*cgbPaletteSize))
+	buffer(core, bessCoreOBJPals, make([]byte, 8*cgbPaletteSize))
+
+	first := len(data)
+	block := func(name string, content []byte) {
+		data = append(data, name...)
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(content)))
+		data = append(data, content...)
+	}
+
+	block("NAME", []byte("test"))
+	block("CORE", core)
+	block("END ", nil)
+
+	data = binary.LittleEndian.AppendUint32(data, uint32(first))
+
+	return append(data, bessMagic...)
+}
+
+func TestParseBESS(t *testing.T) {
+	vram := make([]byte, 2*VRAMBankSize)
+	vram[TileSize] = 0xFF              // tile 1, top row in colour 1
+	vram[bgMapLow] = 1                 // top left tile of the map
+	vram[VRAMBankSize+bgMapLow] = 0x21 // CGB attributes: X-flipped, palette 1
+
+	state := bessState{
+		model:   "CC  ",
+		version: bessVersion,
+		io:      map[int]byte{regLCDC: 0x91, regSCX: 13, regSCY: 7, regBGP: 0xE4, regWX: 7, regWY: 100},
+		vram:    vram,
+	}
+
+	s, err := ParseSaveState(state.bess())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !s.CGB() || s.LCDC != 0x91 || s.SCX != 13 || s.SCY != 7 || s.BGP != 0xE4 || s.WX != 7 || s.WY != 100 {
+		t.Errorf("CGB %v, LCDC $%02X, SCX %d, SCY %d, BGP $%02X, WX %d, WY %d", s.CGB(), s.LCDC, s.SCX, s.SCY, s.BGP, s.WX, s.WY)
+	}
+
+	if got := s.Alignment(); got != (Alignment{X: 3, Y: 1}) {
+		t.Errorf("alignment %+v, want x=3,y=1", got)
+	}
+
+	if len(s.VRAM.Banks) != 2 || s.VRAM.Banks[1][bgMapLow] != 0x21 || len(s.OAM) != oamEntries*oamEntrySize {
+		t.Errorf("%d VRAM banks, %d bytes of OAM", len(s.VRAM.Banks), len(s.OAM))
+	}
+}
+
+func TestSaveStateScreen(t *testing.T) {
+	vram := make([]byte, VRAMBankSize)
+	copy(vram[TileSize:], []byte{0x80, 0x00}) // tile 1, top left pixel in colour 1
+	vram[bgMapLow] = 1
+
+	state := bessState{model: "GD  ", version: bessVersion, io: map[int]byte{regLCDC: 0x91, regBGP: 0x1B}, vram: vram}
+
+	s, err := ParseSaveState(state.bess())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The colour numbers are drawn, whatever BGP $1B shows them in
+	screen := s.Screen()
+	if got := screen.RGBAAt(0, 0); got != GreyPalette[1] {
+		t.Errorf("pixel (0,0) is %v, want %v", got, GreyPalette[1])
+	}
+
+	if got := screen.RGBAAt(1, 0); got != GreyPalette[0] {
+		t.Errorf("pixel (1,0) is %v, want %v", got, GreyPalette[0])
+	}
+
+	if s.CGB() || !s.Window().Empty() {
+		t.Errorf("CGB %v, window %v", s.CGB(), s.Window())
+	}
+}
+
+func TestParseBESSErrors(t *testing.T) {
+	good := bessState{model: "GD  ", version: bessVersion, vram: make([]byte, VRAMBankSize)}.bess()
+
+	cut := append([]byte(nil), good...)
+	binary.LittleEndian.PutUint32(cut[len(cut)-bessFooterSize:], uint32(len(cut)-bessFooterSize-6))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"PNG", []byte("\x89PNG\r\n\x1a\n")},
+		{"no footer", good[:len(good)-1]},
+		{"version 2", bessState{model: "GD  ", version: 2, vram: make([]byte, VRAMBankSize)}.bess()},
+		{"VRAM size", bessState{model: "GD  ", version: bessVersion, vram: make([]byte, 100)}.bess()},
+		{"block cut short", cut},
+		{"no CORE", []byte("END \x00\x00\x00\x00\x00\x00\x00\x00BESS")},
+	}
+
+	for _, test := range tests {
+		if _, err := ParseSaveState(test.data); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+}
+
+// mgba makes a raw mGBA save state, with some extra data after it like mGBA writes
+func (s bessState) mgba(model byte) []byte {
+	data := make([]byte, mgbaSize+100)
+	binary.LittleEndian.PutUint32(data, mgbaMagic+2)
+	data[mgbaModel] = model
+
+	for register, v := range s.io {
+		data[mgbaIO+register] = v
+	}
+
+	copy(data[mgbaVRAM:], s.vram)
+	binary.LittleEndian.PutUint16(data[mgbaPalettes+cgbPaletteSize+2:], 0x001F) // BG palette 1, colour 1 in red
+
+	return data
+}
+
+// mgbaPNG puts the save state in a gbAs chunk of a PNG, the way mGBA does by default
+func mgbaPNG(state []byte) []byte {
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+	_, _ = w.Write(state)
+	_ = w.Close()
+
+	png := []byte(pngSignature)
+	chunk := func(name string, content []byte) {
+		png = binary.BigEndian.AppendUint32(png, uint32(len(content)))
+		png = append(png, name...)
+		png = append(png, content...)
+		png = binary.BigEndian.AppendUint32(png, crc32.ChecksumIEEE(append([]byte(name), content...)))
+	}
+
+	chunk("IHDR", make([]byte, 13))
+	chunk("IDAT", []byte{1, 2, 3})
+	chunk(mgbaChunk, compressed.Bytes())
+	chunk("IEND", nil)
+
+	return png
+}
+
+func TestParseMGBA(t *testing.T) {
+	vram := make([]byte, 2*VRAMBankSize)
+	copy(vram[TileSize:], []byte{0x80, 0x00}) // tile 1, top left pixel in colour 1
+	vram[bgMapLow] = 1
+	vram[VRAMBankSize+bgMapLow] = 0x01 // CGB attributes: palette 1
+
+	state := bessState{io: map[int]byte{regLCDC: 0x91, regSCX: 13, regSCY: 7, regBGP: 0x1B, regWX: 7, regWY: 100}, vram: vram}
+
+	for name, data := range map[string][]byte{"raw": state.mgba(0x80), "PNG": mgbaPNG(state.mgba(0x80))} {
+		s, err := ParseSaveState(data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		if !s.CGB() || s.Model != "CC  " || s.LCDC != 0x91 || s.SCX != 13 || s.SCY != 7 || s.BGP != 0x1B || s.WX != 7 || s.WY != 100 {
+			t.Errorf("%s: CGB %v, model %q, LCDC $%02X, SCX %d, SCY %d, BGP $%02X, WX %d, WY %d",
+				name, s.CGB(), s.Model, s.LCDC, s.SCX, s.SCY, s.BGP, s.WX, s.WY)
+		}
+
+		if len(s.OAM) != oamEntries*oamEntrySize || len(s.BGPalettes) != 64 || len(s.OBJPalettes) != 64 {
+			t.Errorf("%s: %d bytes of OAM, %d and %d bytes of palettes", name, len(s.OAM), len(s.BGPalettes), len(s.OBJPalettes))
+		}
+
+		// The tile at SCX 13, SCY 7 is drawn from the top left tile of the map, in palette 1
+		s.SCX, s.SCY = 0, 0
+		if got := s.Screen().RGBAAt(0, 0); got != from555(0x001F) {
+			t.Errorf("%s: pixel (0,0) is %v, want red", name, got)
+		}
+	}
+
+	s, err := ParseSaveState(state.mgba(0x00))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.SCX, s.SCY = 0, 0
+	if s.CGB() || len(s.VRAM.Banks) != 1 || s.Screen().RGBAAt(0, 0) != GreyPalette[1] {
+		t.Errorf("DMG: CGB %v, %d VRAM banks, pixel (0,0) %v", s.CGB(), len(s.VRAM.Banks), s.Screen().RGBAAt(0, 0))
+	}
+}
+
+func TestParseMGBAErrors(t *testing.T) {
+	state := bessState{vram: make([]byte, 2*VRAMBankSize)}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"cut short", state.mgba(0x00)[:mgbaSize-1]},
+		{"unknown model", state.mgba(0x10)},
+		{"PNG without a state", mgbaPNG(nil)[:len(pngSignature)]},
+		{"PNG with another state", mgbaPNG([]byte("not a state"))},
+		{"PNG chunk cut short", mgbaPNG(state.mgba(0x00))[:100]},
+	}
+
+	for _, test := range tests {
+		if _, err := ParseSaveState(test.data); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+}
+
+// bgb makes a BGB save state out of blocks, in the given order
+func bgb(blocks ...string) func(contents ...[]byte) []byte {
+	return func(contents ...[]byte) []byte {
+		var data []byte
+		for i, name := range blocks {
+			data = append(data, name...)
+			data = append(data, 0)
+			data = binary.LittleEndian.AppendUint32(data, uint32(len(contents[i])))
+			data = append(data, contents[i]...)
+		}
+
+		return data
+	}
+}
+
+func TestParseBGB(t *testing.T) {
+	io := make([]byte, bgbIOSize)
+	io[regLCDC], io[regSCX], io[regSCY], io[regBGP] = 0x91, 13, 7, 0xFC
+
+	vram := make([]byte, 2*VRAMBankSize)
+	copy(vram[TileSize:], []byte{0x80, 0x00}) // tile 1, top left pixel in colour 1
+	vram[bgMapLow] = 1
+	vram[VRAMBankSize+bgMapLow] = 0x01 // CGB attributes: palette 1
+
+	palettes := make([]byte, 8*cgbPaletteSize)
+	binary.LittleEndian.PutUint16(palettes[cgbPaletteSize+2:], 0x001F) // BG palette 1, colour 1 in red
+
+	blocks := bgb("HEADER", bgbIO, bgbVRAM, bgbOAM, bgbBGPals, bgbOBJPals)
+
+	s, err := ParseSaveState(blocks([]byte("BGB"), io, vram, make([]byte, 0xA0), palettes, palettes))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !s.CGB() || s.LCDC != 0x91 || s.SCX != 13 || s.SCY != 7 || s.BGP != 0xFC || len(s.OAM) != oamEntries*oamEntrySize {
+		t.Errorf("CGB %v, LCDC $%02X, SCX %d, SCY %d, BGP $%02X, %d bytes of OAM", s.CGB(), s.LCDC, s.SCX, s.SCY, s.BGP, len(s.OAM))
+	}
+
+	s.SCX, s.SCY = 0, 0
+	if got := s.Screen().RGBAAt(0, 0); got != from555(0x001F) {
+		t.Errorf("CGB: pixel (0,0) is %v, want red", got)
+	}
+
+	// A DMG has a single VRAM bank, and BGP $FC, which shows colour 1 like colour 0, isn't used
+	s, err = ParseSaveState(blocks([]byte("BGB"), io, vram[:VRAMBankSize], nil, nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.SCX, s.SCY = 0, 0
+	if s.CGB() || s.Screen().RGBAAt(0, 0) != GreyPalette[1] {
+		t.Errorf("DMG: CGB %v, pixel (0,0) %v", s.CGB(), s.Screen().RGBAAt(0, 0))
+	}
+}
+
+func TestParseBGBErrors(t *testing.T) {
+	io := make([]byte, bgbIOSize)
+	vram := make([]byte, VRAMBankSize)
+
+	cut := bgb(bgbIO, bgbVRAM)(io, vram)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"no VRAM", bgb(bgbIO)(io)},
+		{"VRAM size", bgb(bgbIO, bgbVRAM)(io, vram[:100])},
+		{"no I/O registers", bgb(bgbVRAM)(vram)},
+		{"block cut short", cut[:len(cut)-1]},
+		{"name without a zero", []byte("VRAM")},
+		{"text", []byte("not a save state\n")},
+	}
+
+	for _, test := range tests {
+		if _, err := ParseSaveState(test.data); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package gbgfx
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+
+	"github.com/nfnt/resize"
+)
+
+// nativeSizes are the resolutions of the screenshots that are recognised when
+// scaled up: the Gameboy screen, the Super Gameboy with its border, and the
+// whole background map of an emulator debugger
+var nativeSizes = []image.Point{
+	{X: gbScreenXRes, Y: gbScreenYRes},
+	{X: sgbXRes, Y: sgbYRes},
+	{X: bgMapRes, Y: bgMapRes},
+}
+
+// scaleFactor returns how many times bigger than one of the native resolutions
+// the image is, if it's a whole number, and that resolution.
+// Any other size isn't scaled, so it's its own native resolution.
+func scaleFactor(img image.Image) (int, image.Point, error) {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+
+	for _, size := range nativeSizes {
+		if w > 0 && w%size.X == 0 && h%size.Y == 0 && w/size.X == h/size.Y {
+			return w / size.X, size, nil
+		}
+	}
+
+	return 1, image.Point{X: w, Y: h}, nil
+}
+
+// screenShapes are the native resolutions whose shape gives away a screenshot
+// scaled by a fraction: the Gameboy screen (10:9) and the Super Gameboy (8:7)
+var screenShapes = []image.Point{
+	{X: gbScreenXRes, Y: gbScreenYRes},
+	{X: sgbXRes, Y: sgbYRes},
+}
+
+// checkFraction refuses an image with the shape of the screen that isn't a whole
+// multiple of it: it was scaled by a fraction (e.g. 1.5x), so its pixels aren't the
+// ones of the Gameboy anymore, even when its size happens to be whole tiles.
+func checkFraction(img image.Image) error {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+
+	for _, size := range screenShapes {
+		if w*size.Y == h*size.X && w%size.X != 0 {
+			return fmt.Errorf("it is %dx%d, %dx%d scaled %.2gx: only whole scales (2x, 3x, ...) can be reduced, "+
+				"save it unscaled or use --crop for part of the screen", w, h, size.X, size.Y, float64(w)/float64(size.X))
+		}
+	}
+
+	return nil
+}
+
+// checkBlocks makes sure every factor x factor block of pixels is a single colour,
+// which is what scaling with nearest neighbour does. Smoothing filters blend the
+// colours at the edges of the blocks, and those colours aren't in the palette.
+func checkBlocks(img image.Image, factor int) error {
+	bounds := img.Bounds()
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += factor {
+		for x := bounds.Min.X; x < bounds.Max.X; x += factor {
+			want := img.At(x, y)
+
+			for by := y; by < y+factor && by < bounds.Max.Y; by++ {
+				for bx := x; bx < x+factor && bx < bounds.Max.X; bx++ {
+					if img.At(bx, by) != want {
+						return fmt.Errorf("scaled %dx, but the pixels of the block at (%d,%d) aren't all the same colour: "+
+							"the screenshot was smoothed, save it unfiltered or with nearest neighbour scaling", factor, x-bounds.Min.X, y-bounds.Min.Y)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// downscale reduces an image scaled by a whole number back to the native resolution
+func downscale(img image.Image, factor int) (image.Image, error) {
+	if err := checkBlocks(img, factor); err != nil {
+		return nil, err
+	}
+
+	w, h := img.Bounds().Dx()/factor, img.Bounds().Dy()/factor
+	small := resize.Resize(uint(w), uint(h), img, resize.NearestNeighbor)
+
+	// Keep it RGBA whatever resize returns, the tiles are encoded from RGBA
+	native := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(native, native.Bounds(), small, small.Bounds().Min, draw.Src)
+
+	return native, nil
+}
@@ -0,0 +1,67 @@
+package gbgfx
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestNewScreenshotScale(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name   string
+		native image.Point
+		factor int
+		screen image.Point // the size of the screenshot once reduced
+	}{
+		{"screen", image.Pt(160, 144), 1, image.Pt(160, 144)},
+		{"screen at 2x", image.Pt(160, 144), 2, image.Pt(160, 144)},
+		{"screen at 4x", image.Pt(160, 144), 4, image.Pt(160, 144)},
+		{"Super Gameboy at 3x", image.Pt(256, 224), 3, image.Pt(160, 144)}, // the border is cropped off
+		{"BG map at 2x", image.Pt(256, 256), 2, image.Pt(256, 256)},
+		{"part of the screen", image.Pt(80, 64), 1, image.Pt(80, 64)},
+	}
+
+	for _, test := range tests {
+		img := greyImage(rnd, test.native.X*test.factor, test.native.Y*test.factor, test.factor)
+
+		s, err := NewScreenshot(img)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if s.Scale != test.factor || s.Bounds().Size() != test.screen {
+			t.Errorf("%s: %v scaled %dx, want %v scaled %dx", test.name, s.Bounds().Size(), s.Scale, test.screen, test.factor)
+			continue
+		}
+
+		// Every pixel is the top left one of its block
+		offset := image.Point{}
+		if s.Border != nil {
+			offset = image.Pt(sgbScreenX, sgbScreenY)
+		}
+
+		for y := 0; y < test.screen.Y; y++ {
+			for x := 0; x < test.screen.X; x++ {
+				if got, want := s.At(x, y), img.At((offset.X+x)*test.factor, (offset.Y+y)*test.factor); got != want {
+					t.Fatalf("%s: pixel (%d,%d) is %v, want %v", test.name, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestNewScreenshotSmoothed(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	// A filter blends the colours at the edges of the blocks
+	img := greyImage(rnd, 320, 288, 2)
+	img.SetRGBA(101, 50, color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xFF})
+
+	if _, err := NewScreenshot(img); err == nil {
+		t.Error("no error for a smoothed screenshot")
+	}
+}
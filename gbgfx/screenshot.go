@@ -0,0 +1,218 @@
+package gbgfx
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Screenshot is an in-game screenshot, or part of one, in the native resolution
+// of the Gameboy and in RGBA colours
+type Screenshot struct {
+	image.Image
+
+	// Scale is how many times bigger than the native resolution the image was
+	Scale int
+
+	// Border is the whole 256x224 picture of a Super Gameboy screenshot,
+	// the screen is cropped out of its middle
+	Border image.Image
+
+	// CGB tells that the screenshot is from a Gameboy Color game: every tile
+	// may have 4 colours of its own, instead of the 4 shades of the DMG palette
+	CGB bool
+}
+
+// LoadScreenshot reads a PNG screenshot from the disk
+func LoadScreenshot(path string) (*Screenshot, error) {
+	return LoadScreenshotCrop(path, image.Rectangle{})
+}
+
+// LoadScreenshotCrop reads a PNG screenshot from the disk and keeps only the crop
+// rectangle of it, in the pixels of the file. An empty rectangle keeps the whole image.
+func LoadScreenshotCrop(path string, crop image.Rectangle) (*Screenshot, error) {
+	img, err := readImageFromFilePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	factor := 1
+
+	if !crop.Empty() {
+		img, factor, err = cropFile(img, crop)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	// The part of a capture the crop cuts may have any size, the whole file may not
+	s, err := newScreenshot(img, !crop.Empty())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if factor > 1 {
+		s.Scale = factor
+	}
+
+	return s, nil
+}
+
+// cropFile cuts the crop rectangle, in the pixels of the file, out of img.
+// A screenshot scaled up as a whole is reduced to its native resolution first,
+// so any part of it can be cut, not just one that is a native size itself:
+// the crop must be made of whole scaled pixels then, and the scale is returned.
+// Otherwise the part is checked for scaling on its own, like the screen in a
+// capture of the emulator window.
+func cropFile(img image.Image, crop image.Rectangle) (image.Image, int, error) {
+	cropped, err := Crop(img, crop)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	factor, _, err := scaleFactor(img)
+	if err != nil || factor == 1 {
+		return cropped, 1, err
+	}
+
+	if crop.Min.X%factor != 0 || crop.Min.Y%factor != 0 || crop.Max.X%factor != 0 || crop.Max.Y%factor != 0 {
+		return nil, 0, fmt.Errorf("the screenshot is scaled %dx, so the crop %d,%d,%d,%d must be multiples of %d",
+			factor, crop.Min.X, crop.Min.Y, crop.Dx(), crop.Dy(), factor)
+	}
+
+	if err := checkColor(img); err != nil {
+		return nil, 0, err
+	}
+
+	native, err := downscale(img, factor)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cropped, err = Crop(native, image.Rect(crop.Min.X/factor, crop.Min.Y/factor, crop.Max.X/factor, crop.Max.Y/factor))
+
+	return cropped, factor, err
+}
+
+// NewScreenshot makes sure the image can be used as a screenshot.
+// Screenshots scaled up by a whole number (2x, 3x, ...) with nearest neighbour
+// are reduced back to their native resolution, and the screen of a Super Gameboy
+// screenshot is cropped out of its border. The shape of the screen scaled by a
+// fraction (e.g. 240x216) is refused. Other sizes are fine as long as they are
+// whole tiles, e.g. part of the screen or the 256x256 background map of a debugger.
+func NewScreenshot(img image.Image) (*Screenshot, error) {
+	return newScreenshot(img, false)
+}
+
+// newScreenshot is NewScreenshot, but a cropped image may have the shape of the
+// screen without being a whole multiple of it
+func newScreenshot(img image.Image, cropped bool) (*Screenshot, error) {
+	if err := checkColor(img); err != nil {
+		return nil, err
+	}
+
+	// A cropped image starts where the crop does, the tiles are cut from (0,0)
+	if min := img.Bounds().Min; min != (image.Point{}) {
+		moved := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+		draw.Draw(moved, moved.Bounds(), img, min, draw.Src)
+		img = moved
+	}
+
+	if !cropped {
+		if err := checkFraction(img); err != nil {
+			return nil, err
+		}
+	}
+
+	factor, size, err := scaleFactor(img)
+	if err != nil {
+		return nil, err
+	}
+
+	if factor > 1 {
+		img, err = downscale(img, factor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Screenshot{Image: img, Scale: factor}
+
+	if size == (image.Point{X: sgbXRes, Y: sgbYRes}) {
+		s.Border = img
+		s.Image = cropSGBScreen(img)
+	}
+
+	if err := checkResolution(s.Image); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// ApplyShades redraws a DMG screenshot taken with any palette in the greys of
+// TileImage, with the shade of every colour in shades, so it can be searched.
+// Colours that aren't in shades are an error.
+func (s *Screenshot) ApplyShades(shades ShadeMap) error {
+	bounds := s.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c := color.RGBAModel.Convert(s.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA)
+
+			shade, ok := shades[c]
+			if !ok {
+				return fmt.Errorf("unknown colour #%02X%02X%02X at pixel (%d,%d), it isn't in the palette", c.R, c.G, c.B, x, y)
+			}
+
+			img.SetRGBA(x, y, GreyPalette[shade])
+		}
+	}
+
+	s.Image = img
+
+	return nil
+}
+
+// Tiles returns the whole 8x8 tiles of the screenshot, row by row, when the
+// background grid starts at align. The partial tiles on the edges are dropped.
+func (s *Screenshot) Tiles(align Alignment) []image.Image {
+	return split8x8(cropImage(s.Image, align))
+}
+
+// UniqueTiles returns the tiles of the screenshot in 2BPP format when the
+// background grid starts at align, without the duplicates. Tiles that are
+// flipped copies of each other count as duplicates too.
+func (s *Screenshot) UniqueTiles(align Alignment) ([]Tile, error) {
+	// Step 1: Split the image into sub-images of 8x8 pixels (tiles), so the tile grid
+	//         of the screenshot lines up with the background grid of the game
+	tiles := s.Tiles(align)
+
+	// Step 2: From the all the tiles, remove the duplicates
+	uniqueTiles := saveUniqueTiles(tiles)
+
+	if s.CGB {
+		uniqueTiles = withFewColours(uniqueTiles)
+	}
+
+	// Step 3:  These tiles are in RGBA format, so we need to convert them to 2BPP
+	// 			before we can compare them to the original gameboy tileset
+	origCodeTiles, err := getHexCodes(uniqueTiles, s.encode)
+	if err != nil {
+		return nil, err
+	}
+
+	return removeDuplicateTiles(origCodeTiles), nil
+}
+
+// encode converts a tile of the screenshot to 2BPP
+func (s *Screenshot) encode(tile image.Image) (Tile, error) {
+	if s.CGB {
+		code, _, err := EncodeCGBTile(tile)
+		return code, err
+	}
+
+	return EncodeTile(tile)
+}
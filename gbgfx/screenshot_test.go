@@ -0,0 +1,123 @@
+package gbgfx
+
+import (
+	"image"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// greyImage returns a w x h image of random greys, in blocks of factor x factor
+// pixels, like a screenshot scaled up with nearest neighbour
+func greyImage(rnd *rand.Rand, w, h, factor int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y += factor {
+		for x := 0; x < w; x += factor {
+			c := GreyPalette[rnd.Intn(len(GreyPalette))]
+
+			for by := y; by < y+factor && by < h; by++ {
+				for bx := x; bx < x+factor && bx < w; bx++ {
+					img.SetRGBA(bx, by, c)
+				}
+			}
+		}
+	}
+
+	return img
+}
+
+// savePNG saves the image in the temporary directory of the test
+func savePNG(t *testing.T, img image.Image) string {
+	path := filepath.Join(t.TempDir(), "screen.png")
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestNewScreenshotFraction(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		w, h, factor int
+		ok           bool
+	}{
+		{240, 216, 1, false}, // the screen at 1.5x
+		{80, 72, 1, false},   // the screen at 0.5x
+		{384, 336, 1, false}, // the Super Gameboy at 1.5x
+		{160, 144, 1, true},
+		{480, 432, 3, true},
+		{160, 72, 1, true},  // part of the screen
+		{384, 384, 1, true}, // the background map is square, it may be any part of it
+	}
+
+	for _, test := range tests {
+		_, err := NewScreenshot(greyImage(rnd, test.w, test.h, test.factor))
+		if ok := err == nil; ok != test.ok {
+			t.Errorf("%dx%d: error %v, want ok %v", test.w, test.h, err, test.ok)
+		}
+	}
+}
+
+func TestLoadScreenshotCrop(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	scaled := greyImage(rnd, 480, 432, 3)
+	path := savePNG(t, scaled)
+
+	// The crop is in the pixels of the file, and reduced with the whole file
+	s, err := LoadScreenshotCrop(path, image.Rect(0, 0, 240, 216))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Bounds().Dx() != 80 || s.Bounds().Dy() != 72 || s.Scale != 3 {
+		t.Fatalf("%v scaled %dx, want 80x72 scaled 3x", s.Bounds(), s.Scale)
+	}
+
+	for y := 0; y < 72; y++ {
+		for x := 0; x < 80; x++ {
+			if got, want := s.At(x, y), scaled.At(3*x, 3*y); got != want {
+				t.Fatalf("pixel (%d,%d) is %v, want %v", x, y, got, want)
+			}
+		}
+	}
+
+	for _, crop := range []image.Rectangle{image.Rect(1, 0, 241, 216), image.Rect(0, 0, 481, 432)} {
+		if _, err := LoadScreenshotCrop(path, crop); err == nil {
+			t.Errorf("crop %v: no error", crop)
+		}
+	}
+
+	// The screen in a capture of the emulator window is cut out, then reduced
+	capture := greyImage(rnd, 400, 330, 1)
+	screen := greyImage(rnd, 320, 288, 2)
+	for y := 0; y < 288; y++ {
+		for x := 0; x < 320; x++ {
+			capture.Set(10+x, 40+y, screen.At(x, y))
+		}
+	}
+
+	if s, err = LoadScreenshotCrop(savePNG(t, capture), image.Rect(10, 40, 330, 328)); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Bounds().Dx() != 160 || s.Bounds().Dy() != 144 || s.Scale != 2 {
+		t.Errorf("capture: %v scaled %dx, want 160x144 scaled 2x", s.Bounds(), s.Scale)
+	}
+
+	// A crop may have any shape, as long as it's whole tiles
+	if _, err = LoadScreenshotCrop(savePNG(t, greyImage(rnd, 400, 330, 1)), image.Rect(0, 0, 240, 216)); err != nil {
+		t.Errorf("240x216 crop: %v", err)
+	}
+}
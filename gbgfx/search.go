@@ -0,0 +1,332 @@
+package gbgfx
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Flip is the transform the hardware applied to a tile from the ROM before
+// it appeared on screen (OAM attribute bits 5/6, or the CGB BG map attributes)
+type Flip int
+
+const (
+	NoFlip Flip = iota
+	FlipX
+	FlipY
+	FlipXY
+)
+
+// Flips lists every transform, in the order they are tried
+var Flips = []Flip{NoFlip, FlipX, FlipY, FlipXY}
+
+func (f Flip) String() string {
+	switch f {
+	case FlipX:
+		return "X-flipped"
+	case FlipY:
+		return "Y-flipped"
+	case FlipXY:
+		return "XY-flipped"
+	default:
+		return "not flipped"
+	}
+}
+
+// Flip returns a flipped copy of the tile.
+// Every row is two bytes, so a horizontal flip reverses the bits of each byte
+// and a vertical flip reverses the order of the rows.
+func (t Tile) Flip(f Flip) Tile {
+	var flipped Tile
+
+	for row := 0; row < len(t)/2; row++ {
+		src := row
+		if f == FlipY || f == FlipXY {
+			src = len(t)/2 - 1 - row
+		}
+
+		low, high := t[2*src], t[2*src+1]
+		if f == FlipX || f == FlipXY {
+			low, high = bits.Reverse8(low), bits.Reverse8(high)
+		}
+
+		flipped[2*row], flipped[2*row+1] = low, high
+	}
+
+	return flipped
+}
+
+// IdentityBGP is the BGP register value that displays colour i as shade i
+const IdentityBGP = 0xE4
+
+// AllBGPs returns the 24 BGP values that display the four colours as four
+// different shades, starting with the identity one
+func AllBGPs() []byte {
+	bgps := []byte{IdentityBGP}
+
+	for bgp := 0; bgp < 256; bgp++ {
+		if bgp != IdentityBGP && IsPermutationBGP(byte(bgp)) {
+			bgps = append(bgps, byte(bgp))
+		}
+	}
+
+	return bgps
+}
+
+// IsPermutationBGP tells if bgp maps every colour to a different shade.
+// Otherwise two colours look the same on screen, and the tile can't be told apart.
+func IsPermutationBGP(bgp byte) bool {
+	var used [4]bool
+
+	for i := 0; i < 4; i++ {
+		shade := (bgp >> (2 * i)) & 0x03
+		if used[shade] {
+			return false
+		}
+
+		used[shade] = true
+	}
+
+	return true
+}
+
+// ParseBGP parses a BGP register value in hex (e.g. E4, 0xE4 or $E4)
+func ParseBGP(v string) (byte, error) {
+	hex := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(v), "0x"), "$")
+
+	bgp, err := strconv.ParseUint(hex, 16, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid BGP value %q, expected a hex byte (e.g. E4): %w", v, err)
+	}
+
+	if !IsPermutationBGP(byte(bgp)) {
+		return 0, fmt.Errorf("BGP value %q must map the 4 colours to 4 different shades", v)
+	}
+
+	return byte(bgp), nil
+}
+
+// Remap takes a tile where every pixel holds its shade (i.e. as displayed with
+// the identity palette) and returns the colour indices that show the same
+// shades when the game has written bgp to the BGP/OBP register.
+func (t Tile) Remap(bgp byte) Tile {
+	// colourOf[shade] is the colour index that bgp displays as shade
+	var colourOf [4]byte
+	for i := byte(0); i < 4; i++ {
+		colourOf[(bgp>>(2*i))&0x03] = i
+	}
+
+	var remapped Tile
+
+	for row := 0; row < len(t)/2; row++ {
+		low, high := t[2*row], t[2*row+1]
+
+		for x := 0; x < 8; x++ {
+			shade := (high>>(7-x)&0x01)<<1 | low>>(7-x)&0x01
+			colour := colourOf[shade]
+
+			remapped[2*row] |= (colour & 0x01) << (7 - x)
+			remapped[2*row+1] |= (colour >> 1) << (7 - x)
+		}
+	}
+
+	return remapped
+}
+
+// Match is a location in the ROM where a tile of the screenshot was found
+type Match struct {
+	Offset     int       // offset in the ROM file
+	ScreenTile Tile      // the tile of the screenshot, with every pixel holding its shade
+	Align      Alignment // alignment of the screenshot the tile was found in
+	Flip       Flip      // transform that turns the ROM tile into the screen tile
+	BGP        byte      // palette register value that turns the ROM colours into the screen shades
+}
+
+// Address returns the offset in the ROM file as a hex string, followed by
+// its BANK:ADDR location (e.g. 0x121D8 (04:61D8))
+func (m Match) Address() string {
+	return fmt.Sprintf("0x%X (%s)", m.Offset, BankAddress(m.Offset))
+}
+
+// SearchOptions tune how tiles are searched in the ROM
+type SearchOptions struct {
+	// BGPs are the palette register values to try, all of AllBGPs() if empty
+	BGPs []byte
+	// AllOccurrences returns every location of a tile, not just the first one
+	AllOccurrences bool
+}
+
+// findTileAddresses finds the addresses of tiles in the ROM.
+// Every tile is searched as it is, and also X, Y and XY flipped, because the
+// game may display a tile mirrored. Flipping twice gives back the original,
+// so the transform applied to the screen tile is also the one the game applied to the ROM tile.
+// The shades of the screenshot are turned back into colour indices with each of
+// the candidate bgps in turn, until one of them finds the tile. The palette that
+// worked is tried first for the next tiles, since a screen usually uses one palette.
+// Unless allOccurrences is set, only the first occurrence of every transformed tile is returned.
+func findTileAddresses(uniqCodeTiles []Tile, index *Index, bgps []byte, allOccurrences bool) []Match {
+	var matches []Match
+
+	bgps = append([]byte(nil), bgps...)
+
+	// Search for each tile in the screenshot
+	for _, code := range uniqCodeTiles {
+		// Palettes that only differ in shades the tile doesn't use give the same bytes
+		tried := make(map[Tile]bool)
+
+		for i, bgp := range bgps {
+			found := make(map[int]bool)
+
+			for _, f := range Flips {
+				tile := code.Remap(bgp).Flip(f)
+				if tried[tile] {
+					continue
+				}
+
+				tried[tile] = true
+
+				// Search for the tile in the rom, the offsets come back in ascending order
+				offsets := index.Lookup(tile)
+				if !allOccurrences && len(offsets) > 1 {
+					// No reason to keep the other ones, if you only want the first
+					offsets = offsets[:1]
+				}
+
+				for _, j := range offsets {
+					// Append the match, unless a symmetric tile matched there already
+					if !found[j] {
+						found[j] = true
+						matches = append(matches, Match{Offset: j, ScreenTile: code, Flip: f, BGP: bgp})
+					}
+				}
+			}
+
+			if len(found) > 0 {
+				// Move the palette that worked to the front
+				copy(bgps[1:i+1], bgps[:i])
+				bgps[0] = bgp
+
+				break
+			}
+		}
+	}
+
+	return matches
+}
+
+// Occurrences are all the locations in the ROM where one tile of the screenshot
+// was found, from the most to the least likely location of the real graphics
+type Occurrences struct {
+	ScreenTile Tile
+	Matches    []Match
+	Scores     []int
+}
+
+// neighbourDistance is how far (in bytes) another matched tile can be to count as a neighbour
+const neighbourDistance = 16 * rangeLength
+
+// RankOccurrences groups the matches per screen tile and sorts every group from
+// the most to the least likely location of the real graphics.
+// Tiles are usually stored at 16-byte-aligned offsets, right next to the other
+// tiles of the same screen, while coincidental byte runs (e.g. a blank tile in
+// the header area) are alone. So every occurrence scores one point for every
+// other screen tile found close by at the same 16-byte phase, plus one more
+// point if it is 16-byte aligned.
+func RankOccurrences(matches []Match) []Occurrences {
+	var groups []Occurrences
+	groupOf := make(map[Tile]int)
+
+	for _, match := range matches {
+		i, ok := groupOf[match.ScreenTile]
+		if !ok {
+			i = len(groups)
+			groupOf[match.ScreenTile] = i
+			groups = append(groups, Occurrences{ScreenTile: match.ScreenTile})
+		}
+
+		groups[i].Matches = append(groups[i].Matches, match)
+	}
+
+	// The matches of every 16-byte phase in ROM order, so the neighbours of a
+	// match are found with a binary search instead of comparing every pair
+	type placed struct {
+		offset int
+		group  int
+	}
+
+	var byPhase [rangeLength][]placed
+
+	for g, group := range groups {
+		for _, match := range group.Matches {
+			phase := match.Offset % rangeLength
+			byPhase[phase] = append(byPhase[phase], placed{offset: match.Offset, group: g})
+		}
+	}
+
+	for phase := range byPhase {
+		bucket := byPhase[phase]
+		sort.Slice(bucket, func(i, j int) bool { return bucket[i].offset < bucket[j].offset })
+	}
+
+	var neighbours []int
+
+	for g := range groups {
+		group := &groups[g]
+		group.Scores = make([]int, len(group.Matches))
+
+		for i, match := range group.Matches {
+			if match.Offset%rangeLength == 0 {
+				group.Scores[i]++
+			}
+
+			// Every other screen tile found within neighbourDistance at the same phase
+			bucket := byPhase[match.Offset%rangeLength]
+			start := sort.Search(len(bucket), func(k int) bool { return bucket[k].offset >= match.Offset-neighbourDistance })
+
+			neighbours = neighbours[:0]
+
+			for k := start; k < len(bucket) && bucket[k].offset <= match.Offset+neighbourDistance; k++ {
+				if h := bucket[k].group; h != g && !containsInt(neighbours, h) {
+					neighbours = append(neighbours, h)
+				}
+			}
+
+			group.Scores[i] += len(neighbours)
+		}
+
+		sort.Stable(byScore{group})
+	}
+
+	return groups
+}
+
+// containsInt tells if the list holds n
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+
+	return false
+}
+
+// byScore sorts the occurrences of a tile by descending score
+type byScore struct {
+	*Occurrences
+}
+
+func (s byScore) Len() int {
+	return len(s.Matches)
+}
+
+func (s byScore) Less(i, j int) bool {
+	return s.Scores[i] > s.Scores[j]
+}
+
+func (s byScore) Swap(i, j int) {
+	s.Matches[i], s.Matches[j] = s.Matches[j], s.Matches[i]
+	s.Scores[i], s.Scores[j] = s.Scores[j], s.Scores[i]
+}
@@ -0,0 +1,151 @@
+package gbgfx
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// pixel returns the colour of the pixel (x,y) of the tile
+func pixel(t Tile, x, y int) byte {
+	return t[2*y]>>(7-x)&1 | t[2*y+1]>>(7-x)&1<<1
+}
+
+// randomTile returns a tile of random colours
+func randomTile(rnd *rand.Rand) Tile {
+	var t Tile
+	rnd.Read(t[:])
+
+	return t
+}
+
+func TestFlip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 100; i++ {
+		tile := randomTile(rnd)
+
+		for _, f := range Flips {
+			flipped := tile.Flip(f)
+
+			for y := 0; y < 8; y++ {
+				for x := 0; x < 8; x++ {
+					fx, fy := x, y
+					if f == FlipX || f == FlipXY {
+						fx = 7 - x
+					}
+
+					if f == FlipY || f == FlipXY {
+						fy = 7 - y
+					}
+
+					if pixel(flipped, fx, fy) != pixel(tile, x, y) {
+						t.Fatalf("% X %s: pixel (%d,%d) isn't at (%d,%d)", tile, f, x, y, fx, fy)
+					}
+				}
+			}
+
+			if flipped.Flip(f) != tile {
+				t.Errorf("% X %s twice isn't the tile", tile, f)
+			}
+		}
+	}
+}
+
+func TestRemap(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for _, bgp := range AllBGPs() {
+		for i := 0; i < 20; i++ {
+			shades := randomTile(rnd)
+			colours := shades.Remap(bgp)
+
+			// Displaying the colours with bgp gives the shades back
+			for y := 0; y < 8; y++ {
+				for x := 0; x < 8; x++ {
+					if shade := bgp >> (2 * pixel(colours, x, y)) & 3; shade != pixel(shades, x, y) {
+						t.Fatalf("BGP $%02X, % X: pixel (%d,%d) shows shade %d, want %d", bgp, shades, x, y, shade, pixel(shades, x, y))
+					}
+				}
+			}
+		}
+	}
+
+	if tile := randomTile(rnd); tile.Remap(IdentityBGP) != tile {
+		t.Errorf("the identity BGP changes % X", tile)
+	}
+}
+
+func TestAllBGPs(t *testing.T) {
+	bgps := AllBGPs()
+	if len(bgps) != 24 || bgps[0] != IdentityBGP {
+		t.Fatalf("%d BGPs starting with $%02X, want 24 starting with $E4", len(bgps), bgps[0])
+	}
+
+	seen := make(map[byte]bool)
+	for _, bgp := range bgps {
+		if seen[bgp] || !IsPermutationBGP(bgp) {
+			t.Errorf("BGP $%02X is repeated or not a permutation", bgp)
+		}
+
+		seen[bgp] = true
+	}
+}
+
+func TestParseBGP(t *testing.T) {
+	for v, want := range map[string]byte{"E4": 0xE4, "0xe4": 0xE4, "$1B": 0x1B, "d2": 0xD2} {
+		if got, err := ParseBGP(v); err != nil || got != want {
+			t.Errorf("%q: $%02X, %v, want $%02X", v, got, err, want)
+		}
+	}
+
+	for _, v := range []string{"", "E", "100", "XY", "00", "E0"} {
+		if _, err := ParseBGP(v); err == nil {
+			t.Errorf("%q: no error", v)
+		}
+	}
+}
+
+func TestRankOccurrences(t *testing.T) {
+	a, b, c, d := Tile{1}, Tile{2}, Tile{3}, Tile{4}
+
+	matches := []Match{
+		{Offset: 0x0134, ScreenTile: a}, // in the header, alone and off the grid
+		{Offset: 0x4000, ScreenTile: a},
+		{Offset: 0x4010, ScreenTile: b},
+		{Offset: 0x4021, ScreenTile: b}, // next to the others, at another phase
+		{Offset: 0x4020, ScreenTile: c},
+		{Offset: 0x7F00, ScreenTile: c}, // on the grid, but alone
+		{Offset: 0x5000, ScreenTile: d},
+		{Offset: 0x6000, ScreenTile: d},
+	}
+
+	want := []struct {
+		tile    Tile
+		offsets []int
+		scores  []int
+	}{
+		{a, []int{0x4000, 0x0134}, []int{3, 0}},
+		{b, []int{0x4010, 0x4021}, []int{3, 0}},
+		{c, []int{0x4020, 0x7F00}, []int{3, 1}},
+		{d, []int{0x5000, 0x6000}, []int{1, 1}}, // a tie keeps the ROM order
+	}
+
+	groups := RankOccurrences(matches)
+	if len(groups) != len(want) {
+		t.Fatalf("%d groups, want %d", len(groups), len(want))
+	}
+
+	for i, w := range want {
+		group := groups[i]
+		if group.ScreenTile != w.tile || len(group.Matches) != len(w.offsets) {
+			t.Errorf("group %d: tile % X with %d matches, want % X with %d", i, group.ScreenTile, len(group.Matches), w.tile, len(w.offsets))
+			continue
+		}
+
+		for j, offset := range w.offsets {
+			if group.Matches[j].Offset != offset || group.Scores[j] != w.scores[j] {
+				t.Errorf("group %d, match %d: 0x%X scored %d, want 0x%X scored %d", i, j, group.Matches[j].Offset, group.Scores[j], offset, w.scores[j])
+			}
+		}
+	}
+}
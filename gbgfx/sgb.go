@@ -0,0 +1,259 @@
+package gbgfx
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// SGBTileSize is the size of an 8x8 tile of the Super Gameboy border: the SNES
+// 4BPP format, the low two bit planes row by row and then the high two
+const SGBTileSize = 32
+
+// cropSGBScreen cuts the Gameboy screen out of the middle of the Super Gameboy border
+func cropSGBScreen(img image.Image) image.Image {
+	min := img.Bounds().Min.Add(image.Point{X: sgbScreenX, Y: sgbScreenY})
+
+	screen := image.NewRGBA(image.Rect(0, 0, gbScreenXRes, gbScreenYRes))
+	draw.Draw(screen, screen.Bounds(), img, min, draw.Src)
+
+	return screen
+}
+
+// ReverseSGBColours redraws the screen of a Super Gameboy screenshot in the
+// greys of TileImage. The Super Gameboy colours every 8x8 cell of the screen
+// with one of 4 palettes, so the same shade has a different colour in different
+// cells. In a cell with 4 colours, the lightest is shade 0 and the darkest shade 3.
+// The colours of the other cells take the shade they had most often in those,
+// or, if they are never in one, the shade of their brightness in the screenshot.
+// It returns the palettes of the cells with 4 colours.
+func (s *Screenshot) ReverseSGBColours() ([]TilePalette, error) {
+	cells := split8x8(s.Image)
+
+	votes := make(map[color.RGBA]*[colorsPerTile]int)
+	var palettes []TilePalette
+	seen := make(map[TilePalette]bool)
+
+	for _, cell := range cells {
+		colours := tileColours(cell)
+		if len(colours) != colorsPerTile {
+			continue
+		}
+
+		var palette TilePalette
+		for shade, c := range colours {
+			if votes[c] == nil {
+				votes[c] = new([colorsPerTile]int)
+			}
+
+			votes[c][shade]++
+			palette[shade] = c
+		}
+
+		if !seen[palette] {
+			seen[palette] = true
+			palettes = append(palettes, palette)
+		}
+	}
+
+	fallback, _, err := AutoShades(s.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, gbScreenXRes, gbScreenYRes))
+
+	for i, cell := range cells {
+		colours := tileColours(cell)
+
+		shades := make(ShadeMap)
+		for shade, c := range colours {
+			switch {
+			case len(colours) == colorsPerTile:
+				shades[c] = byte(shade)
+			case votes[c] != nil:
+				shades[c] = mostVoted(votes[c])
+			default:
+				shades[c] = fallback[c]
+			}
+		}
+
+		x, y := (i%tilesPerRow)*8, (i/tilesPerRow)*8
+
+		for py := 0; py < 8; py++ {
+			for px := 0; px < 8; px++ {
+				c := color.RGBAModel.Convert(cell.At(px, py)).(color.RGBA)
+				img.SetRGBA(x+px, y+py, GreyPalette[shades[c]])
+			}
+		}
+	}
+
+	s.Image = img
+
+	return palettes, nil
+}
+
+// mostVoted returns the shade with the most votes
+func mostVoted(votes *[colorsPerTile]int) byte {
+	best := 0
+	for shade, n := range votes {
+		if n > votes[best] {
+			best = shade
+		}
+	}
+
+	return byte(best)
+}
+
+// BorderMatch is a tile of the Super Gameboy border found in the ROM
+type BorderMatch struct {
+	X      int // position of the tile in the 32x28 tiles of the border
+	Y      int
+	Offset int  // offset of the 4BPP tile in the ROM file
+	Flip   Flip // transform that turns the ROM tile into the tile of the border
+}
+
+// pattern is a tile with its colours numbered in the order they first appear,
+// so two tiles have the same pattern whatever colour each number has
+type pattern [pixelsPerTile]byte
+
+// newPattern numbers the colours of the 64 pixels of a tile, row by row
+func newPattern(pixels [pixelsPerTile]uint32) (pattern, int) {
+	var p pattern
+
+	number := make(map[uint32]byte)
+	for i, pixel := range pixels {
+		n, ok := number[pixel]
+		if !ok {
+			n = byte(len(number))
+			number[pixel] = n
+		}
+
+		p[i] = n
+	}
+
+	return p, len(number)
+}
+
+// pattern4BPP is newPattern for a 4BPP tile, without a map since there are only 16 colours,
+// because it runs at every offset of the ROM
+func pattern4BPP(tile []byte) pattern {
+	var p pattern
+	var number [16]byte
+	next := byte(1)
+
+	for i, pixel := range decode4BPP(tile) {
+		if number[pixel] == 0 {
+			number[pixel] = next
+			next++
+		}
+
+		p[i] = number[pixel] - 1
+	}
+
+	return p
+}
+
+// decode4BPP returns the colour number of every pixel of a SNES 4BPP tile
+func decode4BPP(tile []byte) [pixelsPerTile]uint32 {
+	var pixels [pixelsPerTile]uint32
+
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			bit := uint(7 - x)
+			pixels[y*8+x] = uint32(tile[2*y]>>bit&1) |
+				uint32(tile[2*y+1]>>bit&1)<<1 |
+				uint32(tile[16+2*y]>>bit&1)<<2 |
+				uint32(tile[16+2*y+1]>>bit&1)<<3
+		}
+	}
+
+	return pixels
+}
+
+// minBorderColours is how many colours a tile of the border needs to be searched,
+// the patterns of simpler tiles are all over the ROM
+const minBorderColours = 3
+
+// FindSGBBorder searches the ROM for the tiles of the Super Gameboy border, the way
+// the game sends them with CHR_TRN: 4BPP SNES tiles. The colours of the border are
+// unknown, so a tile matches the ROM wherever the same pixels have the same colour
+// number, whatever the number. Tiles with fewer than 3 colours aren't searched, and
+// games that compress their border won't be found at all.
+func (r *ROM) FindSGBBorder(s *Screenshot) []BorderMatch {
+	if s.Border == nil {
+		return nil
+	}
+
+	type position struct {
+		x, y int
+		flip Flip
+	}
+
+	wanted := make(map[pattern][]position)
+
+	for i, tile := range split8x8(s.Border) {
+		x, y := i%(sgbXRes/8), i/(sgbXRes/8)
+
+		// The cells behind the Gameboy screen are transparent
+		if x >= sgbScreenX/8 && x < (sgbScreenX+gbScreenXRes)/8 && y >= sgbScreenY/8 && y < (sgbScreenY+gbScreenYRes)/8 {
+			continue
+		}
+
+		for _, f := range Flips {
+			var pixels [pixelsPerTile]uint32
+
+			for py := 0; py < 8; py++ {
+				for px := 0; px < 8; px++ {
+					sx, sy := px, py
+					if f&FlipX != 0 {
+						sx = 7 - px
+					}
+
+					if f&FlipY != 0 {
+						sy = 7 - py
+					}
+
+					pixels[py*8+px] = rgb(color.RGBAModel.Convert(tile.At(sx, sy)).(color.RGBA))
+				}
+			}
+
+			p, colours := newPattern(pixels)
+			if colours < minBorderColours {
+				break
+			}
+
+			wanted[p] = append(wanted[p], position{x: x, y: y, flip: f})
+		}
+	}
+
+	var matches []BorderMatch
+	found := make(map[[2]int]bool)
+
+	for offset := 0; offset+SGBTileSize <= len(r.Data); offset++ {
+		p := pattern4BPP(r.Data[offset : offset+SGBTileSize])
+
+		for _, pos := range wanted[p] {
+			if found[[2]int{pos.x, pos.y}] {
+				continue
+			}
+
+			found[[2]int{pos.x, pos.y}] = true
+			matches = append(matches, BorderMatch{X: pos.x, Y: pos.y, Offset: offset, Flip: pos.flip})
+		}
+	}
+
+	return matches
+}
+
+// Tile4BPPImage renders a SNES 4BPP tile in 16 greys, colour 0 white
+func Tile4BPPImage(tile []byte) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+
+	for i, n := range decode4BPP(tile) {
+		grey := uint8(255 - n*17)
+		img.SetRGBA(i%8, i/8, color.RGBA{R: grey, G: grey, B: grey, A: 0xFF})
+	}
+
+	return img
+}
@@ -0,0 +1,123 @@
+package gbgfx
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+	"testing"
+)
+
+// sgbPalettes are two palettes of the Super Gameboy, lightest first, which share white
+var sgbPalettes = []TilePalette{
+	{{R: 0xF8, G: 0xF8, B: 0xF8, A: 0xFF}, {R: 0xF8, G: 0xC0, B: 0x40, A: 0xFF}, {R: 0xC0, G: 0x40, B: 0x00, A: 0xFF}, {R: 0x40, G: 0x00, B: 0x00, A: 0xFF}},
+	{{R: 0xF8, G: 0xF8, B: 0xF8, A: 0xFF}, {R: 0x80, G: 0xF8, B: 0xF8, A: 0xFF}, {R: 0x00, G: 0x80, B: 0xC0, A: 0xFF}, {R: 0x00, G: 0x00, B: 0x40, A: 0xFF}},
+}
+
+func TestReverseSGBColours(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	// Every cell is coloured with one of the palettes, and the cell at (1,0) only
+	// has two shades, so its colours take the shades they have in the other cells
+	img := image.NewRGBA(image.Rect(0, 0, gbScreenXRes, gbScreenYRes))
+	want := image.NewRGBA(img.Bounds())
+
+	for i := 0; i < tilesPerRow*gbScreenYRes/8; i++ {
+		tile := randomTile(rnd)
+		if i == 1 {
+			// Both bits of every pixel the same, shades 0 and 3
+			for y := 0; y < 8; y++ {
+				tile[2*y+1] = tile[2*y]
+			}
+		}
+
+		palette := sgbPalettes[(i/tilesPerRow)%2]
+		x, y := (i%tilesPerRow)*8, (i/tilesPerRow)*8
+
+		for py := 0; py < 8; py++ {
+			for px := 0; px < 8; px++ {
+				img.SetRGBA(x+px, y+py, palette[pixel(tile, px, py)])
+				want.SetRGBA(x+px, y+py, GreyPalette[pixel(tile, px, py)])
+			}
+		}
+	}
+
+	s := &Screenshot{Image: img}
+
+	palettes, err := s.ReverseSGBColours()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(palettes) != 2 || palettes[0] != sgbPalettes[0] || palettes[1] != sgbPalettes[1] {
+		t.Errorf("palettes %v, want %v", palettes, sgbPalettes)
+	}
+
+	for y := 0; y < gbScreenYRes; y++ {
+		for x := 0; x < gbScreenXRes; x++ {
+			if got := s.Image.At(x, y); got != want.At(x, y) {
+				t.Fatalf("pixel (%d,%d) is %v, want %v", x, y, got, want.At(x, y))
+			}
+		}
+	}
+}
+
+// draw4BPP draws a SNES 4BPP tile flipped by f, colour n in the n-th of 16 colours
+func draw4BPP(img *image.RGBA, x, y int, tile []byte, f Flip) {
+	for i, n := range decode4BPP(tile) {
+		px, py := i%8, i/8
+		if f&FlipX != 0 {
+			px = 7 - px
+		}
+
+		if f&FlipY != 0 {
+			py = 7 - py
+		}
+
+		img.SetRGBA(x+px, y+py, color.RGBA{R: uint8(n * 16), G: uint8(255 - n*8), B: uint8(n * 3), A: 0xFF})
+	}
+}
+
+func TestFindSGBBorder(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	rom := &ROM{Data: make([]byte, 0x8000)}
+	rnd.Read(rom.Data[0x2000:0x2080]) // 4 tiles
+
+	// A border of a single colour, but for the 4 tiles, around a Gameboy screen
+	img := image.NewRGBA(image.Rect(0, 0, sgbXRes, sgbYRes))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{R: 0x30, A: 0xFF}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(sgbScreenX, sgbScreenY, sgbScreenX+gbScreenXRes, sgbScreenY+gbScreenYRes),
+		greyImage(rnd, gbScreenXRes, gbScreenYRes, 1), image.Point{}, draw.Src)
+
+	want := []BorderMatch{
+		{X: 3, Y: 1, Offset: 0x2000, Flip: NoFlip},
+		{X: 30, Y: 2, Offset: 0x2020, Flip: FlipX},
+		{X: 0, Y: 20, Offset: 0x2040, Flip: FlipXY},
+		{X: 12, Y: 26, Offset: 0x2060, Flip: FlipY},
+	}
+
+	for _, m := range want {
+		draw4BPP(img, m.X*8, m.Y*8, rom.Data[m.Offset:m.Offset+SGBTileSize], m.Flip)
+	}
+
+	s, err := NewScreenshot(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Border == nil || s.Bounds().Size() != image.Pt(gbScreenXRes, gbScreenYRes) {
+		t.Fatalf("border %v, screen %v", s.Border != nil, s.Bounds())
+	}
+
+	got := rom.FindSGBBorder(s)
+	if len(got) != len(want) {
+		t.Fatalf("%+v, want %+v", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("match %d is %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
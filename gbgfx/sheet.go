@@ -0,0 +1,118 @@
+package gbgfx
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// SheetOptions tune how tiles are laid out on a sheet
+type SheetOptions struct {
+	Columns int  // tiles per row
+	Spacing int  // pixels between two tiles
+	Borders bool // draw a border around every tile, of the same colour for equivalent tiles
+}
+
+// cellSize returns the space a tile takes on the sheet, including the spacing
+func (o SheetOptions) cellSize(tileWidth, tileHeight int) (int, int) {
+	return tileWidth + o.Spacing, tileHeight + o.Spacing
+}
+
+// TilePosition returns where the top left pixel of the i-th tile is on the sheet
+func (o SheetOptions) TilePosition(i, tileWidth, tileHeight int) image.Point {
+	cellWidth, cellHeight := o.cellSize(tileWidth, tileHeight)
+
+	return image.Point{
+		X: (i%o.Columns)*cellWidth + o.Spacing/2,
+		Y: (i/o.Columns)*cellHeight + o.Spacing/2,
+	}
+}
+
+// validate makes sure the tiles fit on the sheet
+func (o SheetOptions) validate() error {
+	if o.Columns < 1 {
+		return errors.New("a sheet needs at least 1 column")
+	}
+
+	if o.Spacing < 0 {
+		return errors.New("the spacing between tiles can't be negative")
+	}
+
+	if o.Borders && o.Spacing < 2 {
+		return errors.New("borders need a spacing of at least 2 pixels")
+	}
+
+	return nil
+}
+
+// TileSheet lays the tiles out on a single image, row by row
+func TileSheet(tiles []image.Image, opts SheetOptions) (image.Image, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	if len(tiles) == 0 {
+		return nil, errors.New("no tiles to put on the sheet")
+	}
+
+	numRows := (len(tiles) + opts.Columns - 1) / opts.Columns
+
+	return createImageFromTiles(tiles, opts.Columns, numRows, opts), nil
+}
+
+// createImageFromTiles creates a new image from the given tiles
+func createImageFromTiles(tiles []image.Image, tilesPerRow, numRows int, opts SheetOptions) image.Image {
+
+	tileWidth, tileHeight := tiles[0].Bounds().Dx(), tiles[0].Bounds().Dy()
+	cellWidth, cellHeight := opts.cellSize(tileWidth, tileHeight)
+	imgWidth, imgHeight := tilesPerRow*cellWidth, numRows*cellHeight
+
+	output := image.NewRGBA(image.Rect(0, 0, imgWidth, imgHeight))
+
+	// Assign a unique color for each unique tile
+	colors := make([]color.Color, len(tiles))
+	for i := range tiles {
+		if !opts.Borders {
+			break
+		}
+
+		colors[i] = color.RGBA{uint8(i * 29 % 256), uint8(i * 73 % 256), uint8(i * 151 % 256), 255}
+
+		for j := 0; j < i; j++ {
+			if areImagesEquivalent(tiles[i], tiles[j]) {
+				colors[i] = colors[j]
+				break
+			}
+		}
+	}
+
+	for i, tile := range tiles {
+		if i >= tilesPerRow*numRows {
+			break
+		}
+
+		pos := opts.TilePosition(i, tileWidth, tileHeight)
+		x, y := pos.X, pos.Y
+
+		r := image.Rect(x, y, x+tileWidth, y+tileHeight)
+		draw.Draw(output, r, tile, tile.Bounds().Min, draw.Src)
+
+		if !opts.Borders {
+			continue
+		}
+
+		// Draw the perimeter with the assigned color
+		perimeterColor := colors[i]
+		for px := x - 1; px <= x+tileWidth; px++ {
+			output.Set(px, y-1, perimeterColor)          // Top border
+			output.Set(px, y+tileHeight, perimeterColor) // Bottom border
+		}
+		for py := y - 1; py <= y+tileHeight; py++ {
+			output.Set(x-1, py, perimeterColor)         // Left border
+			output.Set(x+tileWidth, py, perimeterColor) // Right border
+		}
+	}
+
+	return output
+}
@@ -0,0 +1,82 @@
+package gbgfx
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestTileSheet(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	var codes []Tile
+	for i := 0; i < 4; i++ {
+		codes = append(codes, randomTile(rnd))
+	}
+
+	codes = append(codes, codes[1].Flip(FlipXY)) // the same tile as the second one
+
+	var tiles []image.Image
+	for _, code := range codes {
+		tiles = append(tiles, TileImage(code[:]))
+	}
+
+	opts := SheetOptions{Columns: 2, Spacing: 2, Borders: true}
+
+	img, err := TileSheet(tiles, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sheet := img.(*image.RGBA)
+	if got := sheet.Bounds().Size(); got != image.Pt(20, 30) {
+		t.Fatalf("the sheet is %v, want 20x30", got)
+	}
+
+	for i, tile := range tiles {
+		pos := opts.TilePosition(i, 8, 8)
+		if want := image.Pt((i%2)*10+1, (i/2)*10+1); pos != want {
+			t.Errorf("tile %d is at %v, want %v", i, pos, want)
+		}
+
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				if got, want := sheet.At(pos.X+x, pos.Y+y), tile.At(x, y); got != want {
+					t.Fatalf("tile %d, pixel (%d,%d) is %v, want %v", i, x, y, got, want)
+				}
+			}
+		}
+	}
+
+	// The border is the pixel around the tile, of the same colour for the same tiles
+	border := func(i int) color.Color {
+		pos := opts.TilePosition(i, 8, 8)
+		return sheet.At(pos.X-1, pos.Y-1)
+	}
+
+	if border(1) != border(4) || border(0) == border(1) || border(2) == border(3) {
+		t.Errorf("borders %v, %v, %v, %v and %v", border(0), border(1), border(2), border(3), border(4))
+	}
+}
+
+func TestTileSheetErrors(t *testing.T) {
+	tiles := []image.Image{TileImage(make([]byte, TileSize))}
+
+	tests := []struct {
+		name  string
+		tiles []image.Image
+		opts  SheetOptions
+	}{
+		{"no tiles", nil, SheetOptions{Columns: 1}},
+		{"no columns", tiles, SheetOptions{}},
+		{"negative spacing", tiles, SheetOptions{Columns: 1, Spacing: -1}},
+		{"borders without spacing", tiles, SheetOptions{Columns: 1, Spacing: 1, Borders: true}},
+	}
+
+	for _, test := range tests {
+		if _, err := TileSheet(test.tiles, test.opts); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+}
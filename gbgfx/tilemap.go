@@ -0,0 +1,294 @@
+package gbgfx
+
+import "image"
+
+// Tilemap is the background of a screenshot rebuilt from the ROM: which ROM tile
+// is drawn at every position of the background grid, and how it's flipped.
+// Only the whole tiles are in it, so a scrolled screenshot has one column
+// and one row less than the tiles of the screen (19x17 instead of 20x18).
+type Tilemap struct {
+	Width   int
+	Height  int
+	Align   Alignment
+	Entries []TilemapEntry // row by row
+}
+
+// TilemapEntry is a position of the tilemap
+type TilemapEntry struct {
+	X     int
+	Y     int
+	Found bool  // the tile was found in the ROM
+	Match Match // where the tile was found
+	Flip  Flip  // transform that turns the ROM tile into the tile at this position
+}
+
+// createTiledArray For every tile, create an array with the size of the tiledImage
+// and fill it with the tile index if the tile is found in the tiledImage
+func createTiledArray(allTiles []image.Image, uniqueTiles []image.Image) []int {
+
+	// Create an array if integers with the size of the tiledImageTiles
+	tiledArray := make([]int, len(allTiles))
+
+	for i, tile := range uniqueTiles {
+		for j, imageTile := range allTiles {
+			if areImagesEquivalent(tile, imageTile) {
+				tiledArray[j] = i
+			}
+		}
+	}
+
+	return tiledArray
+
+}
+
+// Tilemap rebuilds the background of the screenshot, when the background grid
+// starts at align, out of the matches found in the ROM.
+// Every position refers to one of the unique tiles of the screenshot, which may
+// be a flipped copy of the tile at that position, and that unique tile may be
+// a flipped copy of the ROM tile too. Flipping is a XOR of the X and Y bits, so
+// the two transforms add up to the flip of the position.
+func (s *Screenshot) Tilemap(align Alignment, matches []Match) (*Tilemap, error) {
+	tiles := s.Tiles(align)
+
+	uniqueTiles := saveUniqueTiles(tiles)
+
+	if s.CGB {
+		uniqueTiles = withFewColours(uniqueTiles)
+	}
+
+	tiledArray := createTiledArray(tiles, uniqueTiles)
+
+	uniqueCodes, err := getHexCodes(uniqueTiles, s.encode)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first match of every screen tile is the one to use
+	matchOf := make(map[Tile]Match)
+	for i := len(matches) - 1; i >= 0; i-- {
+		matchOf[matches[i].ScreenTile] = matches[i]
+	}
+
+	tilemap := &Tilemap{
+		Width:  (s.Bounds().Dx() - align.X) / 8,
+		Height: (s.Bounds().Dy() - align.Y) / 8,
+		Align:  align,
+	}
+
+	for j, tile := range tiles {
+		entry := TilemapEntry{X: j % tilemap.Width, Y: j / tilemap.Width}
+
+		code, err := s.encode(tile)
+		if err != nil {
+			if !s.CGB {
+				return nil, err
+			}
+
+			// Too many colours for a tile, so it wasn't searched
+			tilemap.Entries = append(tilemap.Entries, entry)
+
+			continue
+		}
+
+		unique := uniqueCodes[tiledArray[j]]
+
+		if match, ok := matchOf[unique]; ok {
+			for _, f := range Flips {
+				if unique.Flip(f) == code {
+					entry.Found = true
+					entry.Match = match
+					entry.Flip = match.Flip ^ f
+
+					break
+				}
+			}
+		}
+
+		tilemap.Entries = append(tilemap.Entries, entry)
+	}
+
+	return tilemap, nil
+}
+
+// minKnownTiles is how many tiles of a row have to be known to search for it,
+// fewer than that would match all over the ROM
+const minKnownTiles = 4
+
+// MapMatch is a location in the ROM where the tile numbers of a tilemap were found
+type MapMatch struct {
+	Offset    int  // offset in the ROM file of the first tile number
+	FirstRow  int  // first row of the tilemap that was found
+	Rows      int  // number of rows of the tilemap that were found
+	Stride    int  // bytes from one row to the next in the ROM
+	Base      byte // tile number of the tile stored at RefOffset
+	RefOffset int  // offset in the ROM file of the tile the numbers are relative to
+
+	// The game may address the background tiles from $8000 (unsigned tile
+	// numbers) or from $9000 (signed tile numbers, i.e. $8800-$97FF), and the
+	// tile numbers found only fit a contiguous block of VRAM in some of them
+	Mode8000 bool
+	Mode8800 bool
+}
+
+// VRAM8000 returns the VRAM address of the reference tile with $8000 addressing
+func (m MapMatch) VRAM8000() int {
+	return 0x8000 + int(m.Base)*16
+}
+
+// VRAM8800 returns the VRAM address of the reference tile with $8800 addressing
+func (m MapMatch) VRAM8800() int {
+	return 0x9000 + int(int8(m.Base))*16
+}
+
+// tileNumbers turns the ROM offsets of the tilemap into tile numbers, relative
+// to the first tile of the block of graphics most of the screen comes from.
+// It assumes the game copies that block to VRAM as it is, so tiles that are N
+// tiles apart in the ROM are N tile numbers apart in the map. Tiles from
+// elsewhere (another 16-byte phase, or more than 256 tiles away) are unknown (-1).
+func (t *Tilemap) tileNumbers() ([]int, int) {
+	// Find the 16-byte phase most tiles are stored at
+	var phaseCount [rangeLength]int
+	for _, entry := range t.Entries {
+		if entry.Found {
+			phaseCount[entry.Match.Offset%rangeLength]++
+		}
+	}
+
+	phase := 0
+	for p := range phaseCount {
+		if phaseCount[p] > phaseCount[phase] {
+			phase = p
+		}
+	}
+
+	refOffset := -1
+	for _, entry := range t.Entries {
+		if entry.Found && entry.Match.Offset%rangeLength == phase && (refOffset < 0 || entry.Match.Offset < refOffset) {
+			refOffset = entry.Match.Offset
+		}
+	}
+
+	numbers := make([]int, len(t.Entries))
+	for i, entry := range t.Entries {
+		numbers[i] = -1
+
+		if entry.Found && entry.Match.Offset%rangeLength == phase {
+			if number := (entry.Match.Offset - refOffset) / rangeLength; number < 256 {
+				numbers[i] = number
+			}
+		}
+	}
+
+	return numbers, refOffset
+}
+
+// FindTilemap searches the ROM for the tile numbers of the tilemap, i.e. for
+// where the game stores the layout of the screen. The tile numbers are only
+// known relative to each other, so a match is any run of bytes that differ from
+// them by the same amount (the number of the reference tile, e.g. $80 higher
+// with $8800 addressing).
+// The whole screen is searched first, with its rows stored back to back or
+// 32 bytes apart (like the BG map in VRAM). If it isn't there, every row is
+// searched on its own.
+func (r *ROM) FindTilemap(t *Tilemap) []MapMatch {
+	numbers, refOffset := t.tileNumbers()
+	if refOffset < 0 {
+		return nil
+	}
+
+	var matches []MapMatch
+
+	searched := make(map[int]bool)
+
+	for _, stride := range []int{t.Width, tilesPerRow, 32} {
+		// A map as wide as the screen, or as the whole BG map, is searched once
+		if stride < t.Width || searched[stride] {
+			continue
+		}
+
+		searched[stride] = true
+		matches = append(matches, r.findTileNumbers(numbers, t.Width, 0, t.Height, stride)...)
+	}
+
+	if len(matches) > 0 {
+		for i := range matches {
+			matches[i].RefOffset = refOffset
+		}
+
+		return matches
+	}
+
+	for row := 0; row < t.Height; row++ {
+		matches = append(matches, r.findTileNumbers(numbers, t.Width, row, 1, 0)...)
+	}
+
+	for i := range matches {
+		matches[i].RefOffset = refOffset
+	}
+
+	return matches
+}
+
+// findTileNumbers searches the ROM for rows firstRow to firstRow+rows of the
+// tile numbers, stored stride bytes apart
+func (r *ROM) findTileNumbers(numbers []int, width int, firstRow int, rows int, stride int) []MapMatch {
+	// The pattern is every known tile number with its position in the ROM
+	type known struct {
+		pos    int
+		number int
+	}
+
+	var pattern []known
+	distinct := make(map[int]bool)
+	maxNumber := 0
+
+	for y := firstRow; y < firstRow+rows; y++ {
+		for x := 0; x < width; x++ {
+			if number := numbers[y*width+x]; number >= 0 {
+				pattern = append(pattern, known{pos: (y-firstRow)*stride + x, number: number})
+				distinct[number] = true
+
+				if number > maxNumber {
+					maxNumber = number
+				}
+			}
+		}
+	}
+
+	// A row of the same tile matches every run of equal bytes
+	if len(pattern) < minKnownTiles*rows || len(distinct) < 2 {
+		return nil
+	}
+
+	length := pattern[len(pattern)-1].pos + 1
+
+	var matches []MapMatch
+
+	for offset := 0; offset+length <= len(r.Data); offset++ {
+		base := r.Data[offset+pattern[0].pos] - byte(pattern[0].number)
+
+		found := true
+		for _, k := range pattern[1:] {
+			if r.Data[offset+k.pos] != base+byte(k.number) {
+				found = false
+				break
+			}
+		}
+
+		if !found {
+			continue
+		}
+
+		matches = append(matches, MapMatch{
+			Offset:   offset,
+			FirstRow: firstRow,
+			Rows:     rows,
+			Stride:   stride,
+			Base:     base,
+			Mode8000: int(base)+maxNumber <= 0xFF,
+			Mode8800: int(base^0x80)+maxNumber <= 0xFF,
+		})
+	}
+
+	return matches
+}
@@ -0,0 +1,128 @@
+package gbgfx
+
+import (
+	"image"
+	"image/draw"
+	"math/rand"
+	"testing"
+)
+
+// drawnTile is a tile of the ROM drawn on the screen, and how it's flipped
+type drawnTile struct {
+	index int
+	flip  Flip
+}
+
+// flippedScreen returns a ROM holding 24 tiles, at the multiples of 16 from 0x4000,
+// and a screen built out of them flipped every way, whose background grid starts
+// at align. The tiles of the whole positions are returned row by row.
+func flippedScreen(t *testing.T, rnd *rand.Rand, align Alignment) (*ROM, *Screenshot, []drawnTile) {
+	rom := &ROM{Data: make([]byte, 0x8000)}
+
+	var tiles []Tile
+	for i := 0; i < 24; i++ {
+		tiles = append(tiles, randomTile(rnd))
+		copy(rom.Data[0x4000+i*TileSize:], tiles[i][:])
+	}
+
+	var positions []drawnTile
+
+	img := image.NewRGBA(image.Rect(0, 0, gbScreenXRes, gbScreenYRes))
+	for y := align.Y - 8; y < gbScreenYRes; y += 8 {
+		for x := align.X - 8; x < gbScreenXRes; x += 8 {
+			p := drawnTile{index: rnd.Intn(len(tiles)), flip: Flips[rnd.Intn(len(Flips))]}
+			flipped := tiles[p.index].Flip(p.flip)
+			draw.Draw(img, image.Rect(x, y, x+8, y+8), TileImage(flipped[:]), image.Point{}, draw.Src)
+
+			if x >= 0 && y >= 0 && x+8 <= gbScreenXRes && y+8 <= gbScreenYRes {
+				positions = append(positions, p)
+			}
+		}
+	}
+
+	s, err := NewScreenshot(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rom, s, positions
+}
+
+func TestTilemap(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for _, align := range []Alignment{{0, 0}, {3, 5}} {
+		rom, s, positions := flippedScreen(t, rnd, align)
+
+		matches, err := rom.FindScreenshot(s, align, SearchOptions{BGPs: []byte{IdentityBGP}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tilemap, err := s.Tilemap(align, matches)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		width, height := (gbScreenXRes-align.X)/8, (gbScreenYRes-align.Y)/8
+		if tilemap.Width != width || tilemap.Height != height || len(tilemap.Entries) != len(positions) {
+			t.Fatalf("%v: %dx%d with %d entries, want %dx%d with %d", align, tilemap.Width, tilemap.Height, len(tilemap.Entries), width, height, len(positions))
+		}
+
+		for i, entry := range tilemap.Entries {
+			p := positions[i]
+			if entry.X != i%width || entry.Y != i/width || !entry.Found || entry.Match.Offset != 0x4000+p.index*TileSize || entry.Flip != p.flip {
+				t.Errorf("%v: entry %d is (%d,%d), found %v at 0x%X %s, want 0x%X %s",
+					align, i, entry.X, entry.Y, entry.Found, entry.Match.Offset, entry.Flip, 0x4000+p.index*TileSize, p.flip)
+			}
+		}
+	}
+}
+
+func TestFindTilemap(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for _, stride := range []int{32, 64} {
+		rom, s, positions := flippedScreen(t, rnd, Alignment{})
+
+		matches, err := rom.FindScreenshot(s, Alignment{}, SearchOptions{BGPs: []byte{IdentityBGP}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tilemap, err := s.Tilemap(Alignment{}, matches)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The game numbers the tiles from $80, the numbers are relative to the first tile on the screen
+		first := len(positions)
+		for i, p := range positions {
+			rom.Data[0x6000+(i/tilemap.Width)*stride+i%tilemap.Width] = byte(0x80 + p.index)
+			if p.index < first {
+				first = p.index
+			}
+		}
+
+		// Rows 64 bytes apart aren't searched as a whole, so every row is found on its own
+		want := []MapMatch{{Offset: 0x6000, Rows: tilemap.Height, Stride: 32}}
+		if stride == 64 {
+			want = nil
+			for row := 0; row < tilemap.Height; row++ {
+				want = append(want, MapMatch{Offset: 0x6000 + row*stride, FirstRow: row, Rows: 1})
+			}
+		}
+
+		got := rom.FindTilemap(tilemap)
+		if len(got) != len(want) {
+			t.Fatalf("stride %d: %d matches, want %d", stride, len(got), len(want))
+		}
+
+		for i, w := range want {
+			w.Base, w.RefOffset, w.Mode8000, w.Mode8800 = byte(0x80+first), 0x4000+first*TileSize, true, true
+			if got[i] != w {
+				t.Errorf("stride %d: match %d is %+v, want %+v", stride, i, got[i], w)
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package gbgfx
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// checkResolution makes sure the screenshot is made of whole 8x8 tiles, like the
+// 160x144 pixels of the Gameboy screen
+func checkResolution(src image.Image) error {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+
+	if w == 0 || h == 0 || w%8 != 0 || h%8 != 0 {
+		return fmt.Errorf("it is %dx%d, which isn't a multiple of 8 (160x144, or scaled up by a whole number)", w, h)
+	}
+
+	return nil
+}
+
+// Crop returns the part of img inside r, in the coordinates of img
+func Crop(img image.Image, r image.Rectangle) (image.Image, error) {
+	r = r.Add(img.Bounds().Min)
+	if !r.In(img.Bounds()) {
+		return nil, fmt.Errorf("crop %dx%d at (%d,%d) is out of the %dx%d image",
+			r.Dx(), r.Dy(), r.Min.X-img.Bounds().Min.X, r.Min.Y-img.Bounds().Min.Y, img.Bounds().Dx(), img.Bounds().Dy())
+	}
+
+	// Keep the colour model of the image, so it's checked like the whole image
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(r), nil
+	}
+
+	cropped := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(cropped, cropped.Bounds(), img, r.Min, draw.Src)
+
+	return cropped, nil
+}
+
+// ParseCrop parses a crop rectangle given as "X,Y,W,H" (e.g. 0,0,160,72)
+func ParseCrop(v string) (image.Rectangle, error) {
+	var x, y, w, h int
+
+	if _, err := fmt.Sscanf(v, "%d,%d,%d,%d", &x, &y, &w, &h); err != nil {
+		return image.Rectangle{}, fmt.Errorf("invalid crop %q, expected X,Y,W,H (e.g. 0,0,160,72): %w", v, err)
+	}
+
+	if x < 0 || y < 0 || w <= 0 || h <= 0 {
+		return image.Rectangle{}, fmt.Errorf("invalid crop %q, X and Y can't be negative and W and H must be positive", v)
+	}
+
+	return image.Rect(x, y, x+w, y+h), nil
+}
+
+// split8x8 splits the image into whole 8x8 tiles, row by row.
+// Partial tiles on the right and bottom edges are skipped.
+func split8x8(src image.Image) []image.Image {
+	// Iterate over the image pixels and split it into 8x8 sub-images
+	var tiles []image.Image
+
+	for y := 0; y+8 <= src.Bounds().Max.Y; y += 8 {
+		for x := 0; x+8 <= src.Bounds().Max.X; x += 8 {
+			tile := image.NewRGBA(image.Rect(0, 0, 8, 8))
+
+			for i := 0; i < 8; i++ {
+				for j := 0; j < 8; j++ {
+					tile.Set(i, j, src.At(x+i, y+j))
+				}
+			}
+
+			tiles = append(tiles, tile)
+		}
+	}
+
+	return tiles
+}
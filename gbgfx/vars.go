@@ -0,0 +1,30 @@
+package gbgfx
+
+const (
+	width           = 8
+	bitDepth        = 2
+	rangeLength     = 16
+	gbScreenXRes    = 160
+	gbScreenYRes    = 144
+	pixelsPerScreen = gbScreenXRes * gbScreenYRes
+	pixelsPerTile   = 8 * 8
+	tilesPerRow     = gbScreenXRes / 8
+
+	// The Super Gameboy draws the screen in the middle of a 256x224 border
+	sgbXRes    = 256
+	sgbYRes    = 224
+	sgbScreenX = 48
+	sgbScreenY = 40
+
+	// The background map is 32x32 tiles, emulator debuggers show it whole
+	bgMapRes = 256
+)
+
+const (
+	// ScreenWidth is the horizontal resolution of the Gameboy screen
+	ScreenWidth = gbScreenXRes
+	// ScreenHeight is the vertical resolution of the Gameboy screen
+	ScreenHeight = gbScreenYRes
+	// TileSize is the size of an 8x8 tile in 2BPP format
+	TileSize = rangeLength
+)
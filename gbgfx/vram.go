@@ -0,0 +1,135 @@
+package gbgfx
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	// VRAMBankSize is the size of a bank of video RAM, $8000-$9FFF
+	VRAMBankSize = 0x2000
+	// vramAddress is where the video RAM is in the memory map
+	vramAddress = 0x8000
+	// vramTiles is how many tiles fit in $8000-$97FF, the rest of a bank is the BG maps
+	vramTiles = 384
+	// memoryMapSize is the size of a dump of the whole memory map, $0000-$FFFF
+	memoryMapSize = 0x10000
+)
+
+// VRAM is the video RAM of the Gameboy: one bank, or two on the Gameboy Color
+type VRAM struct {
+	Banks [][]byte
+}
+
+// VRAMTile is a tile of the video RAM
+type VRAMTile struct {
+	Tile    Tile
+	Bank    int
+	Address int // $8000-$97F0
+}
+
+func (t VRAMTile) String() string {
+	return fmt.Sprintf("VRAM %d:$%04X", t.Bank, t.Address)
+}
+
+// ReadVRAMDump reads a dump of the video RAM saved by an emulator (see ParseVRAMDump)
+func ReadVRAMDump(path string) (*VRAM, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := ParseVRAMDump(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return v, nil
+}
+
+// ParseVRAMDump tells the dump apart by its size: 8 KB is a bank of video RAM,
+// 16 KB the two banks of the Gameboy Color, and 64 KB the whole memory map,
+// with the video RAM at $8000.
+func ParseVRAMDump(data []byte) (*VRAM, error) {
+	switch len(data) {
+	case VRAMBankSize:
+		return &VRAM{Banks: [][]byte{data}}, nil
+	case 2 * VRAMBankSize:
+		return &VRAM{Banks: [][]byte{data[:VRAMBankSize], data[VRAMBankSize:]}}, nil
+	case memoryMapSize:
+		return &VRAM{Banks: [][]byte{data[vramAddress : vramAddress+VRAMBankSize]}}, nil
+	}
+
+	return nil, fmt.Errorf("a VRAM dump is 8 KB, 16 KB (CGB) or 64 KB (the whole memory), not %d bytes", len(data))
+}
+
+// Tiles returns the 384 tiles of every bank, from $8000 to $97FF, without the blank
+// ones, which would match all over the ROM, and without the duplicates.
+// Tiles that are flipped copies of each other count as duplicates too, since
+// the search tries every flip.
+func (v *VRAM) Tiles() []VRAMTile {
+	var tiles []VRAMTile
+
+	for bank := range v.Banks {
+		for i := 0; i < vramTiles; i++ {
+			tiles = append(tiles, v.tile(bank, i*TileSize))
+		}
+	}
+
+	return uniqueVRAMTiles(tiles)
+}
+
+// tile returns the tile at offset in the bank
+func (v *VRAM) tile(bank, offset int) VRAMTile {
+	t := VRAMTile{Bank: bank, Address: vramAddress + offset}
+	copy(t.Tile[:], v.Banks[bank][offset:])
+
+	return t
+}
+
+// uniqueVRAMTiles drops the blank tiles and the duplicates, flipped or not
+func uniqueVRAMTiles(tiles []VRAMTile) []VRAMTile {
+	var unique []VRAMTile
+	seen := make(map[Tile]bool)
+
+	for _, t := range tiles {
+		if isBlank(t.Tile) || seen[t.Tile] {
+			continue
+		}
+
+		for _, f := range Flips {
+			seen[t.Tile.Flip(f)] = true
+		}
+
+		unique = append(unique, t)
+	}
+
+	return unique
+}
+
+// isBlank tells if every pixel of the tile has the same colour
+func isBlank(t Tile) bool {
+	lo, hi := t[0], t[1]
+	if (lo != 0x00 && lo != 0xFF) || (hi != 0x00 && hi != 0xFF) {
+		return false
+	}
+
+	for row := 0; row < TileSize; row += 2 {
+		if t[row] != lo || t[row+1] != hi {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FindVRAM searches the ROM for the tiles of the video RAM. They hold colour numbers,
+// like the ROM, so only the identity BGP is tried.
+func (r *ROM) FindVRAM(tiles []VRAMTile, allOccurrences bool) []Match {
+	codes := make([]Tile, len(tiles))
+	for i, tile := range tiles {
+		codes[i] = tile.Tile
+	}
+
+	return r.FindTiles(codes, SearchOptions{BGPs: []byte{IdentityBGP}, AllOccurrences: allOccurrences})
+}
@@ -0,0 +1,80 @@
+package gbgfx
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestParseVRAMDump(t *testing.T) {
+	memory := make([]byte, memoryMapSize)
+	memory[vramAddress] = 0xAB
+
+	tests := []struct {
+		name  string
+		data  []byte
+		banks int
+		first byte
+	}{
+		{"DMG", make([]byte, VRAMBankSize), 1, 0},
+		{"CGB", make([]byte, 2*VRAMBankSize), 2, 0},
+		{"memory map", memory, 1, 0xAB},
+	}
+
+	for _, test := range tests {
+		v, err := ParseVRAMDump(test.data)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if len(v.Banks) != test.banks || len(v.Banks[0]) != VRAMBankSize || v.Banks[0][0] != test.first {
+			t.Errorf("%s: %d banks of %d bytes starting with $%02X", test.name, len(v.Banks), len(v.Banks[0]), v.Banks[0][0])
+		}
+	}
+
+	if _, err := ParseVRAMDump(make([]byte, VRAMBankSize+1)); err == nil {
+		t.Error("no error for a dump of 8 KB and 1 byte")
+	}
+}
+
+func TestVRAMTiles(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	a, b := randomTile(rnd), randomTile(rnd)
+	black := Tile{}
+	for i := range black {
+		black[i] = 0xFF
+	}
+
+	bank0, bank1 := make([]byte, VRAMBankSize), make([]byte, VRAMBankSize)
+	copy(bank0[0x10:], a[:])
+	copy(bank0[0x20:], black[:]) // blank
+	flipped := a.Flip(FlipY)
+	copy(bank0[0x30:], flipped[:]) // a duplicate of a
+	copy(bank0[0x1800:], b[:])     // in the BG maps, not a tile
+	copy(bank1[0x17F0:], b[:])     // the last tile of the second bank
+
+	tiles := (&VRAM{Banks: [][]byte{bank0, bank1}}).Tiles()
+
+	want := []VRAMTile{{Tile: a, Bank: 0, Address: 0x8010}, {Tile: b, Bank: 1, Address: 0x97F0}}
+	if len(tiles) != len(want) {
+		t.Fatalf("%v, want %v", tiles, want)
+	}
+
+	for i := range want {
+		if tiles[i] != want[i] {
+			t.Errorf("tile %d is %v, want %v", i, tiles[i], want[i])
+		}
+	}
+
+	// Colour numbers are searched as they are, so they are found with the identity BGP
+	rom := &ROM{Data: make([]byte, 0x8000)}
+	other, flippedB := a.Remap(0x1B), b.Flip(FlipX)
+	copy(rom.Data[0x4100:], other[:]) // a in other colours
+	copy(rom.Data[0x6000:], flippedB[:])
+
+	matches := rom.FindVRAM(tiles, false)
+	if len(matches) != 1 || matches[0].Offset != 0x6000 || matches[0].Flip != FlipX || matches[0].BGP != IdentityBGP {
+		t.Errorf("%+v, want b at 0x6000, X-flipped", matches)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/drpaneas/gbgraphics/gbgfx"
+)
+
+// printHeader prints the cartridge header and anything wrong with it
+func printHeader(h gbgfx.Header, romLength int) {
+	cartridgeType, ok := h.CartridgeTypeName()
+	if !ok {
+		cartridgeType = "unknown"
+	}
+
+	fmt.Printf("Title: %s\n", h.Title)
+	fmt.Printf("Hardware: %s ($%02X), %s ($%02X)\n", h.CGBSupport(), h.CGBFlag, h.SGBSupport(), h.SGBFlag)
+	fmt.Printf("Cartridge: %s ($%02X)\n", cartridgeType, h.CartridgeType)
+
+	if banks := h.ROMBanks(); banks > 0 {
+		fmt.Printf("ROM size: %d KB, %d banks ($%02X)\n", banks*gbgfx.ROMBankSize/1024, banks, h.ROMSize)
+	} else {
+		fmt.Printf("ROM size: unknown ($%02X)\n", h.ROMSize)
+	}
+
+	if ramSize, ok := h.RAMSizeKB(); ok {
+		fmt.Printf("RAM size: %d KB ($%02X)\n", ramSize, h.RAMSize)
+	} else {
+		fmt.Printf("RAM size: unknown ($%02X)\n", h.RAMSize)
+	}
+
+	fmt.Printf("Checksums: header $%02X, global $%04X\n", h.HeaderChecksum, h.GlobalChecksum)
+
+	for _, warning := range h.Warnings(romLength) {
+		fmt.Println("Warning:", warning)
+	}
+}
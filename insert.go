@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"strconv"
+
+	"github.com/drpaneas/gbgraphics/gbgfx"
+)
+
+type insertArgs struct {
+	Rom    string `arg:"positional,required" help:"Path to the ROM file"`
+	Image  string `arg:"required,--img" help:"edited tile or sheet" placeholder:"<PNG>"`
+	Index  string `arg:"--index" help:"JSON index of the sheet [default: the --img path with .json]" placeholder:"<JSON>"`
+	Offset string `arg:"--offset" help:"ROM offset to write the tiles of the image to, one after the other, instead of using an index (e.g. 0x10120)" placeholder:"<OFFSET>"`
+	Output string `arg:"--output" help:"patched ROM file" placeholder:"<FILE>"`
+	IPS    string `arg:"--ips" help:"save the changes as an IPS patch" placeholder:"<FILE>"`
+	BPS    string `arg:"--bps" help:"save the changes as a BPS patch" placeholder:"<FILE>"`
+}
+
+// tileWrite is an edited tile and the ROM offset it goes to
+type tileWrite struct {
+	offset int
+	tile   gbgfx.Tile
+}
+
+// runInsert puts the tiles of an edited image back into the ROM and saves it with fixed checksums,
+// as a ROM or as patches against the original one
+func runInsert(cmd *insertArgs) error {
+	if cmd.Output == "" && cmd.IPS == "" && cmd.BPS == "" {
+		return errors.New("nowhere to save the changes, use --output, --ips or --bps")
+	}
+
+	rom, err := gbgfx.ReadROM(cmd.Rom)
+	if err != nil {
+		return err
+	}
+
+	original := append([]byte(nil), rom.Data...)
+
+	img, err := gbgfx.LoadImage(cmd.Image)
+	if err != nil {
+		return err
+	}
+
+	var writes []tileWrite
+	if cmd.Offset != "" {
+		writes, err = encodeAt(img, cmd.Offset)
+	} else {
+		index := cmd.Index
+		if index == "" {
+			index = sheetIndexPath(cmd.Image)
+		}
+
+		writes, err = encodeSheet(img, index)
+	}
+
+	// Nothing is written unless every tile uses the colours of the palette
+	if err != nil {
+		return err
+	}
+
+	changed := 0
+
+	for _, write := range writes {
+		ok, err := rom.WriteTile(write.offset, write.tile)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			changed++
+			fmt.Printf("'% X' written at location 0x%X (%s)\n", write.tile, write.offset, gbgfx.BankAddress(write.offset))
+		}
+	}
+
+	fmt.Printf("%d of %d tile(s) changed\n", changed, len(writes))
+
+	before, err := rom.Header()
+	if err != nil {
+		return err
+	}
+
+	if err := rom.FixChecksums(); err != nil {
+		return err
+	}
+
+	after, err := rom.Header()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Header checksum $%02X -> $%02X, global checksum $%04X -> $%04X\n",
+		before.HeaderChecksum, after.HeaderChecksum, before.GlobalChecksum, after.GlobalChecksum)
+
+	if cmd.Output != "" {
+		if err := rom.Save(cmd.Output); err != nil {
+			return err
+		}
+
+		fmt.Printf("Patched ROM saved to '%s'\n", cmd.Output)
+	}
+
+	if cmd.IPS != "" {
+		patch, err := gbgfx.MakeIPS(original, rom.Data)
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(cmd.IPS, patch, 0o644); err != nil {
+			return err
+		}
+
+		fmt.Printf("IPS patch saved to '%s'\n", cmd.IPS)
+	}
+
+	if cmd.BPS != "" {
+		if err := os.WriteFile(cmd.BPS, gbgfx.MakeBPS(original, rom.Data), 0o644); err != nil {
+			return err
+		}
+
+		fmt.Printf("BPS patch saved to '%s'\n", cmd.BPS)
+	}
+
+	return nil
+}
+
+// encodeAt encodes the tiles of the image, row by row, to be written one after the other from offset
+func encodeAt(img *image.RGBA, offset string) ([]tileWrite, error) {
+	start, err := strconv.ParseInt(offset, 0, 64)
+	if err != nil || start < 0 {
+		return nil, fmt.Errorf("invalid offset %q", offset)
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx()%8 != 0 || bounds.Dy()%8 != 0 {
+		return nil, fmt.Errorf("image is %dx%d, not a whole number of 8x8 tiles", bounds.Dx(), bounds.Dy())
+	}
+
+	var writes []tileWrite
+
+	for y := 0; y < bounds.Dy(); y += 8 {
+		for x := 0; x < bounds.Dx(); x += 8 {
+			tile, err := encodeTileAt(img, x, y)
+			if err != nil {
+				return nil, err
+			}
+
+			writes = append(writes, tileWrite{offset: int(start) + len(writes)*gbgfx.TileSize, tile: tile})
+		}
+	}
+
+	return writes, nil
+}
+
+// encodeSheet encodes the tiles of a sheet saved by extract, at the places its JSON index says
+func encodeSheet(img *image.RGBA, indexPath string) ([]tileWrite, error) {
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w (use --index or --offset)", err)
+	}
+
+	var doc sheetJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", indexPath, err)
+	}
+
+	if doc.Format != "" && doc.Format != "2bpp" {
+		return nil, fmt.Errorf("%s is an index of %s tiles, only 2bpp tiles can be inserted", indexPath, doc.Format)
+	}
+
+	var writes []tileWrite
+
+	for _, entry := range doc.Tiles {
+		offset, err := strconv.ParseInt(entry.Offset, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset %q of tile %d in %s", entry.Offset, entry.Index, indexPath)
+		}
+
+		tile, err := encodeTileAt(img, entry.X, entry.Y)
+		if err != nil {
+			return nil, fmt.Errorf("tile %d: %w", entry.Index, err)
+		}
+
+		writes = append(writes, tileWrite{offset: int(offset), tile: tile})
+	}
+
+	return writes, nil
+}
+
+// encodeTileAt encodes the 8x8 tile whose top left pixel is at x,y
+func encodeTileAt(img *image.RGBA, x, y int) (gbgfx.Tile, error) {
+	r := image.Rect(x, y, x+8, y+8)
+	if !r.In(img.Bounds()) {
+		return gbgfx.Tile{}, fmt.Errorf("tile at (%d,%d) is out of the %dx%d image", x, y, img.Bounds().Dx(), img.Bounds().Dy())
+	}
+
+	tile, err := gbgfx.EncodeTile(img.SubImage(r))
+	if err != nil {
+		return gbgfx.Tile{}, fmt.Errorf("tile at (%d,%d): %w", x, y, err)
+	}
+
+	return tile, nil
+}
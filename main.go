@@ -2,10 +2,14 @@ package main
 
 import (
 	"fmt"
+	"image"
 	"os"
 	"runtime/debug"
+	"sort"
+	"strings"
 
 	"github.com/alexflint/go-arg"
+	"github.com/drpaneas/gbgraphics/gbgfx"
 )
 
 var Commit = func() string {
@@ -21,11 +25,46 @@ var Commit = func() string {
 }()
 
 type args struct {
-	Rom        string `arg:"positional,required" help:"Path to the ROM file"`
-	Screenshot string `arg:"required,--img" help:"path of in-game screenshot" placeholder:"<SCREENSHOT>"`
-	Output     string `arg:"--output" help:"output file" default:"out.png" placeholder:"<FILE>"`
+	Extract  *extractArgs `arg:"subcommand:extract" help:"extract the graphics of a screenshot from the ROM (default command)"`
+	Cache    *cacheArgs   `arg:"subcommand:cache" help:"list or purge the cached ROM indexes"`
+	Dump     *dumpArgs    `arg:"subcommand:dump" help:"render the raw tile data of the ROM, without a screenshot"`
+	Insert   *insertArgs  `arg:"subcommand:insert" help:"put edited tiles back into the ROM, or save them as a patch"`
+	Apply    *applyArgs   `arg:"subcommand:apply" help:"apply an IPS or BPS patch to a ROM"`
+	CacheDir string       `arg:"--cache-dir" help:"directory of the cached ROM indexes [default: user cache dir]" placeholder:"<DIR>"`
 }
 
+type extractArgs struct {
+	Rom        string  `arg:"positional,required" help:"Path to the ROM file"`
+	Screenshot string  `arg:"--img" help:"path of in-game screenshot" placeholder:"<SCREENSHOT>"`
+	State      string  `arg:"--state" help:"rebuild the screen from a BESS (SameBoy), mGBA or BGB save state instead of a screenshot, with the exact alignment and palette" placeholder:"<FILE>"`
+	VRAM       string  `arg:"--vram" help:"search for every tile of a VRAM dump (8 KB, 16 KB for CGB or 64 KB of memory) instead of a screenshot" placeholder:"<DUMP>"`
+	Output     string  `arg:"--output" help:"output file" default:"out.png" placeholder:"<FILE>"`
+	Crop       string  `arg:"--crop" help:"use only this part of the screenshot, in pixels of the file" placeholder:"<X,Y,W,H>"`
+	Align      string  `arg:"--align" help:"background alignment to search: auto, all or X,Y" default:"auto" placeholder:"<MODE>"`
+	Candidates int     `arg:"--candidates" help:"number of ranked alignment candidates to print in auto mode" default:"5" placeholder:"<N>"`
+	Palette    string  `arg:"--palette" help:"colours of the screenshot: auto, grey, greyscale, original, bgb or 4 hex colours lightest first (e.g. E0F8D0,88C070,346856,081820) [default: the greys or bgb]" placeholder:"<PALETTE>"`
+	Nearest    string  `arg:"--nearest" help:"take the nearest colour of the palette for the colours that aren't in it, by rgb or lab distance" placeholder:"<METRIC>"`
+	MaxDist    float64 `arg:"--max-distance" help:"fail if a pixel is farther than this from the palette with --nearest [default: half the distance between the two closest shades of the palette]" placeholder:"<D>"`
+	BGP        string  `arg:"--bgp" help:"palette register value the game used: auto or a hex byte (e.g. E4)" default:"auto" placeholder:"<BGP>"`
+	All        bool    `arg:"--all" help:"report every ROM occurrence of each tile, ranked, and extract the best one"`
+	NoCache    bool    `arg:"--no-cache" help:"don't read or write the cached ROM index"`
+	Tilemap    string  `arg:"--tilemap" help:"save the background tilemap as PREFIX.tilemap, PREFIX.json and PREFIX.csv" placeholder:"<PREFIX>"`
+	FindMap    bool    `arg:"--find-map" help:"search the ROM for the tile numbers of the background tilemap"`
+	Sheet      string  `arg:"--sheet" help:"save all the tiles, ordered by ROM address, on a single PNG with a JSON index instead of one PNG per tile" placeholder:"<FILE>"`
+	Columns    int     `arg:"--sheet-columns" help:"tiles per row of the sheet" default:"16" placeholder:"<N>"`
+	Spacing    int     `arg:"--sheet-spacing" help:"pixels between the tiles of the sheet" default:"0" placeholder:"<PX>"`
+	Borders    bool    `arg:"--sheet-borders" help:"draw a border around every tile of the sheet, of the same colour for equivalent tiles (needs a spacing of 2 or more)"`
+	CGB        bool    `arg:"--cgb" help:"the screenshot is from a Gameboy Color game: infer the palette of every tile"`
+	FindPals   bool    `arg:"--find-palettes" help:"search the ROM for the inferred CGB palettes (needs --cgb)"`
+	Tolerance  int     `arg:"--palette-tolerance" help:"how much every 5-bit colour channel of a palette may be off in the ROM" default:"1" placeholder:"<N>"`
+	SGBBorder  string  `arg:"--sgb-border" help:"search the ROM for the tiles of the Super Gameboy border and save them on a sheet with a JSON index" placeholder:"<FILE>"`
+	Blocks     string  `arg:"--blocks" help:"save the whole blocks of tile data around the found tiles on a sheet with a JSON index" placeholder:"<FILE>"`
+	BlockLimit int     `arg:"--block-limit" help:"tiles to walk backward and forward from every found tile, 0 for up to the end of the bank" default:"384" placeholder:"<N>"`
+}
+
+// commands are the names of the subcommands
+var commands = []string{"extract", "cache", "dump", "insert", "apply"}
+
 func (args) Description() string {
 	return "GBGraphics - extract graphics from Gameboy ROM using a screenshot"
 }
@@ -37,44 +76,629 @@ func (args) Version() string {
 func main() {
 	var userInput args
 
-	arg.MustParse(&userInput)
+	parser, err := arg.NewParser(arg.Config{}, &userInput)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	err = parser.Parse(withDefaultCommand(os.Args[1:]))
+
+	switch {
+	case err == arg.ErrHelp:
+		if err := parser.WriteHelpForSubcommand(os.Stdout, parser.SubcommandNames()...); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	case err == arg.ErrVersion:
+		fmt.Println(userInput.Version())
+		os.Exit(0)
+	case err != nil:
+		if err := parser.FailSubcommand(err.Error(), parser.SubcommandNames()...); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if userInput.Dump != nil {
+		if err := runDump(userInput.Dump); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if userInput.Insert != nil {
+		if err := runInsert(userInput.Insert); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if userInput.Apply != nil {
+		if err := runApply(userInput.Apply); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	// Without arguments, or with only the top-level options, there's nothing to do
+	if userInput.Extract == nil && userInput.Cache == nil {
+		parser.Fail("a ROM or a command is required")
+	}
+
+	var cacheDir string
+	if userInput.Extract == nil || !userInput.Extract.NoCache {
+		cacheDir, err = gbgfx.CacheDir(userInput.CacheDir)
+		if err != nil {
+			fmt.Println(err, "(use --cache-dir)")
+			os.Exit(1)
+		}
+	}
+
+	if userInput.Cache != nil {
+		if err := runCache(cacheDir, userInput.Cache); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	extract(userInput.Extract, cacheDir)
+}
+
+// withDefaultCommand puts "extract" in front of the arguments, unless they
+// already start with a subcommand or ask for the top-level help or version.
+// The top-level options (and their values) may come before the subcommand,
+// e.g. "gbgraphics --cache-dir DIR cache".
+// So "gbgraphics --img screen.png game.gb" keeps working.
+func withDefaultCommand(arguments []string) []string {
+	i := 0
+
+	for i < len(arguments) {
+		argument := arguments[i]
+
+		if argument == "--cache-dir" {
+			i += 2
+			continue
+		}
+
+		if strings.HasPrefix(argument, "--cache-dir=") || argument == "-h" || argument == "--help" || argument == "--version" {
+			i++
+			continue
+		}
+
+		break
+	}
+
+	if i >= len(arguments) {
+		return arguments
+	}
+
+	for _, command := range commands {
+		if arguments[i] == command {
+			return arguments
+		}
+	}
+
+	return append(append(append([]string(nil), arguments[:i]...), "extract"), arguments[i:]...)
+}
+
+// extract searches the ROM for the tiles of the screenshot and saves them as PNGs.
+// The ROM index is cached in cacheDir, unless it's empty.
+func extract(userInput *extractArgs, cacheDir string) {
+	rom, err := gbgfx.ReadROM(userInput.Rom)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	header, err := rom.Header()
+	if err != nil {
+		fmt.Println("Warning:", err)
+	} else {
+		printHeader(header, len(rom.Data))
+	}
+
+	inputs := 0
+	for _, input := range []string{userInput.Screenshot, userInput.VRAM, userInput.State} {
+		if input != "" {
+			inputs++
+		}
+	}
+
+	if inputs != 1 {
+		fmt.Println("give one of --img, --vram or --state")
+		os.Exit(1)
+	}
+
+	if userInput.VRAM != "" {
+		extractVRAM(userInput, rom, cacheDir)
+		return
+	}
+
+	var state *gbgfx.SaveState
+	var screenshot *gbgfx.Screenshot
+
+	if userInput.State != "" {
+		state, screenshot, err = loadSaveState(userInput)
+	} else {
+		screenshot, err = loadScreenshot(userInput)
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if screenshot.Scale > 1 {
+		fmt.Printf("Screenshot is scaled %dx, reduced to its native resolution\n", screenshot.Scale)
+	}
+
+	if size := screenshot.Bounds().Size(); size != image.Pt(gbgfx.ScreenWidth, gbgfx.ScreenHeight) {
+		fmt.Printf("Screenshot is %dx%d pixels, %dx%d tiles\n", size.X, size.Y, size.X/8, size.Y/8)
+	}
+
+	if screenshot.Border != nil {
+		fmt.Println("Super Gameboy border found, the screen is cropped out of it")
+	}
 
-	outputFilename := userInput.Output
-	screenshot := userInput.Screenshot
+	screenshot.CGB = userInput.CGB
 
-	path := userInput.Rom
-	if path == "" {
-		fmt.Println("No ROM specified!")
+	if err := applyPalette(screenshot, userInput); err != nil {
+		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	romBytes, errReadFile := os.ReadFile(path)
-	if errReadFile != nil {
-		fmt.Println(errReadFile)
+	bgps, err := selectBGPs(userInput.BGP)
+	if err != nil {
+		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	// loop from 0 to 7
-	var locations [][]string
-	for i := 0; i < 8; i++ {
-		locations = append(locations, getTiles(screenshot, romBytes, i))
+	// Hash every 16-byte window of the ROM once, instead of scanning it for every tile
+	if err := rom.LoadIndex(cacheDir); err != nil {
+		fmt.Println("Warning:", err)
+	}
+
+	alignments, err := selectAlignments(rom, screenshot, userInput.Align, userInput.Candidates, bgps)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// Remember which alignment found each address first,
+	// and which one found the most tiles, to rebuild the tilemap.
+	// A ROM tile may be on the screen with more than one palette, so the
+	// tilemap needs the match of every screen tile, not only of every address.
+	var uniqueMatches, screenMatches []gbgfx.Match
+	found := make(map[int]bool)
+
+	var bestAlign gbgfx.Alignment
+	bestCount := 0
+
+	for _, align := range alignments {
+		matches, err := rom.FindScreenshot(screenshot, align, gbgfx.SearchOptions{BGPs: bgps, AllOccurrences: userInput.All})
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if len(matches) == 0 {
+			continue
+		}
+
+		fmt.Printf("Alignment %s: %d tile(s) found\n", align, len(matches))
+
+		if len(matches) > bestCount {
+			bestAlign, bestCount = align, len(matches)
+		}
+
+		screenMatches = append(screenMatches, matches...)
+
+		for _, match := range matches {
+			if !found[match.Offset] {
+				found[match.Offset] = true
+				uniqueMatches = append(uniqueMatches, match)
+			}
+		}
+	}
+
+	if userInput.All {
+		uniqueMatches, screenMatches = bestOccurrences(uniqueMatches)
+	}
+
+	// On the CGB the BGP is the order of the colours of the palette instead
+	if !userInput.CGB {
+		printBGPGroups(uniqueMatches)
+	}
+
+	if userInput.SGBBorder != "" {
+		sheetOpts := gbgfx.SheetOptions{Columns: userInput.Columns, Spacing: userInput.Spacing, Borders: userInput.Borders}
+		if err := saveBorder(userInput.SGBBorder, rom, screenshot, sheetOpts); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	tileNumber := saveMatches(userInput, rom, uniqueMatches, func(match gbgfx.Match) string {
+		return "alignment " + match.Align.String()
+	})
+
+	if state != nil {
+		printSpriteMatches(rom, state)
+	}
+
+	if userInput.Tilemap == "" && !userInput.FindMap && !userInput.CGB {
+		if userInput.FindPals {
+			fmt.Println("Warning: --find-palettes needs --cgb")
+		}
+
+		return
+	}
+
+	tilemap, err := screenshot.Tilemap(bestAlign, screenMatches)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var paletteNumbers []int
+
+	if userInput.CGB {
+		var palettes []gbgfx.CGBPalette
+
+		palettes, paletteNumbers, err = screenshot.Palettes(tilemap)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		printPalettes(palettes, paletteNumbers)
+
+		if userInput.FindPals {
+			printPaletteMatches(rom.FindPalettes(palettes, userInput.Tolerance), palettes)
+		}
+	}
+
+	if userInput.Tilemap != "" {
+		if err := saveTilemap(userInput.Tilemap, tilemap, tileNumber, paletteNumbers); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if userInput.FindMap {
+		printMapMatches(rom.FindTilemap(tilemap))
+	}
+}
+
+// loadScreenshot loads the screenshot of --img, cropped with --crop
+func loadScreenshot(userInput *extractArgs) (*gbgfx.Screenshot, error) {
+	var crop image.Rectangle
+	if userInput.Crop != "" {
+		var err error
+		if crop, err = gbgfx.ParseCrop(userInput.Crop); err != nil {
+			return nil, err
+		}
+	}
+
+	return gbgfx.LoadScreenshotCrop(userInput.Screenshot, crop)
+}
+
+// bestOccurrences keeps only the best ranked occurrence of every searched tile,
+// after printing them all. It returns the best ones without the duplicate ROM
+// tiles, and all of them.
+func bestOccurrences(matches []gbgfx.Match) ([]gbgfx.Match, []gbgfx.Match) {
+	groups := gbgfx.RankOccurrences(matches)
+	printOccurrences(groups)
+
+	var uniqueMatches, best []gbgfx.Match
+	found := make(map[int]bool)
+
+	for _, group := range groups {
+		match := group.Matches[0]
+		best = append(best, match)
+
+		// The same ROM tile may be the best one of several screen tiles, e.g. with two palettes
+		if !found[match.Offset] {
+			found[match.Offset] = true
+			uniqueMatches = append(uniqueMatches, match)
+		}
 	}
 
-	// Create a slice of all the addresses
-	var allAddresses []string
-	for _, location := range locations {
-		allAddresses = append(allAddresses, location...)
+	return uniqueMatches, best
+}
+
+// saveMatches saves the tiles found in the ROM: on a sheet, as blocks, or one PNG per tile.
+// source tells where every tile was searched from. It returns the number of every tile.
+func saveMatches(userInput *extractArgs, rom *gbgfx.ROM, uniqueMatches []gbgfx.Match, source func(gbgfx.Match) string) map[int]int {
+	sheetOpts := gbgfx.SheetOptions{Columns: userInput.Columns, Spacing: userInput.Spacing, Borders: userInput.Borders}
+	matchAt := make(map[int]gbgfx.Match)
+
+	for _, match := range uniqueMatches {
+		matchAt[match.Offset] = match
 	}
 
-	uniqueAddresses := removeDuplicateString(allAddresses)
+	if userInput.Sheet != "" {
+		// Number the tiles in ROM order, the same as on the sheet
+		sort.Slice(uniqueMatches, func(i, j int) bool { return uniqueMatches[i].Offset < uniqueMatches[j].Offset })
+
+		var offsets []int
+		for _, match := range uniqueMatches {
+			offsets = append(offsets, match.Offset)
+		}
+
+		if err := saveSheet(userInput.Sheet, offsets, matchAt, source, sheetOpts, rom.Data); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if userInput.Blocks != "" {
+		if err := saveBlocks(userInput.Blocks, rom, uniqueMatches, userInput.BlockLimit, matchAt, source, sheetOpts); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	tileNumber := make(map[int]int)
+
+	for i, match := range uniqueMatches {
+		tileNumber[match.Offset] = i
+
+		if userInput.Sheet != "" {
+			continue
+		}
 
-	for i, address := range uniqueAddresses {
 		// for every address, get the tile and save it to disk
-		//tile := romBytes[convertHexToInt32(address) : convertHexToInt32(address)+16]
-		//fmt.Printf("Address: %s, Tile: %v\n", address, hex.EncodeToString(tile))
-		if err := processTile(i, address, outputFilename, romBytes, rangeLength, width, bitDepth); err != nil {
+		if err := processTile(i, match, source(match), userInput.Output, rom.Data); err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
 	}
+
+	return tileNumber
+}
+
+// selectAlignments decides which alignments of the background grid to search.
+// The game may have scrolled horizontally (SCX) as well as vertically (SCY),
+// so "all" tries every (x,y) sub-tile phase, while "auto" ranks them by how
+// many of their tiles are in the ROM and only searches the most likely one.
+// Anything else is parsed as X,Y.
+func selectAlignments(rom *gbgfx.ROM, screenshot *gbgfx.Screenshot, mode string, candidates int, bgps []byte) ([]gbgfx.Alignment, error) {
+	switch mode {
+	case "all":
+		return gbgfx.AllAlignments(), nil
+	case "auto":
+		ranked, err := rom.RankAlignments(screenshot, gbgfx.SearchOptions{BGPs: bgps})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ranked) == 0 {
+			return nil, fmt.Errorf("screenshot is too small to contain a single tile")
+		}
+
+		fmt.Println("Candidate alignments:")
+		for i, candidate := range ranked {
+			if i >= candidates {
+				break
+			}
+
+			fmt.Printf("  %d. %s score %.3f\n", i+1, candidate.Align, candidate.Score)
+		}
+
+		return []gbgfx.Alignment{ranked[0].Align}, nil
+	default:
+		align, err := gbgfx.ParseAlignment(mode)
+		if err != nil {
+			return nil, err
+		}
+
+		return []gbgfx.Alignment{align}, nil
+	}
+}
+
+// applyPalette redraws a DMG screenshot in the greys, with the palette of --palette,
+// taking the nearest colour of the palette for the others with --nearest
+func applyPalette(screenshot *gbgfx.Screenshot, userInput *extractArgs) error {
+	if userInput.CGB {
+		if userInput.Palette != "" || userInput.Nearest != "" {
+			fmt.Println("Warning: --palette and --nearest are ignored with --cgb, every tile has its own palette")
+		}
+
+		return nil
+	}
+
+	if userInput.Palette == "" && userInput.Nearest == "" {
+		if screenshot.Border == nil {
+			return nil
+		}
+
+		palettes, err := screenshot.ReverseSGBColours()
+		if err != nil {
+			return err
+		}
+
+		for i, palette := range palettes {
+			fmt.Printf("SGB palette %d: %s\n", i, formatPalette(palette))
+		}
+
+		return nil
+	}
+
+	shades := gbgfx.DefaultShades()
+
+	if userInput.Palette != "" {
+		var err error
+
+		shades, err = selectShades(screenshot, userInput.Palette)
+		if err != nil {
+			return err
+		}
+	}
+
+	if userInput.Nearest == "" {
+		return screenshot.ApplyShades(shades)
+	}
+
+	metric, err := gbgfx.ParseMetric(userInput.Nearest)
+	if err != nil {
+		return err
+	}
+
+	maxDistance := userInput.MaxDist
+	if maxDistance == 0 {
+		maxDistance = metric.DefaultMaxDistance(shades)
+	}
+
+	report, err := screenshot.SnapColours(shades, metric, maxDistance)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d pixel(s) snapped to the nearest colour of the palette, the farthest was %.1f away\n", report.Snapped, report.MaxDistance)
+
+	return nil
+}
+
+// selectShades decides which shade every colour of the screenshot is: "auto"
+// clusters the colours of the screenshot, anything else is parsed as a palette
+func selectShades(screenshot *gbgfx.Screenshot, mode string) (gbgfx.ShadeMap, error) {
+	if mode != "auto" {
+		palette, err := gbgfx.ParsePalette(mode)
+		if err != nil {
+			return nil, err
+		}
+
+		return palette.Shades(), nil
+	}
+
+	shades, palette, err := gbgfx.AutoShades(screenshot)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Palette: %d colour(s) found, lightest to darkest %s\n", len(shades), formatPalette(palette))
+
+	return shades, nil
+}
+
+// formatPalette prints the colours of a palette as hex, the way --palette takes them
+func formatPalette(palette gbgfx.TilePalette) string {
+	var colours []string
+	for _, c := range palette {
+		if c.A == 0 {
+			// No colour has this shade
+			colours = append(colours, "------")
+			continue
+		}
+
+		colours = append(colours, fmt.Sprintf("%02X%02X%02X", c.R, c.G, c.B))
+	}
+
+	return strings.Join(colours, ",")
+}
+
+// selectBGPs decides which palette register values to try when turning the
+// shades of the screenshot back into colour indices: "auto" tries all of them.
+func selectBGPs(mode string) ([]byte, error) {
+	if mode == "auto" {
+		return gbgfx.AllBGPs(), nil
+	}
+
+	bgp, err := gbgfx.ParseBGP(mode)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte{bgp}, nil
+}
+
+// printBGPGroups prints how many of the found tiles were displayed with each palette
+func printBGPGroups(matches []gbgfx.Match) {
+	var order []byte
+	count := make(map[byte]int)
+
+	for _, match := range matches {
+		if count[match.BGP] == 0 {
+			order = append(order, match.BGP)
+		}
+
+		count[match.BGP]++
+	}
+
+	for _, bgp := range order {
+		fmt.Printf("BGP $%02X: %d tile(s)\n", bgp, count[bgp])
+	}
+}
+
+// printPalettes prints the background palettes inferred from a CGB screenshot,
+// and how many positions of the tilemap use each one
+func printPalettes(palettes []gbgfx.CGBPalette, numbers []int) {
+	count := make([]int, len(palettes))
+	for _, n := range numbers {
+		if n >= 0 {
+			count[n]++
+		}
+	}
+
+	for i, palette := range palettes {
+		fmt.Printf("BG palette %d: %s (%d tile(s))\n", i, palette, count[i])
+	}
+
+	if len(palettes) > 8 {
+		fmt.Println("Warning: the CGB only has 8 BG palettes, so some colours were probably changed by the capture")
+	}
+}
+
+// maxPaletteMatches is how many locations of the palettes are printed,
+// a palette with few known colours may match in a lot of places
+const maxPaletteMatches = 20
+
+// printPaletteMatches prints where the palettes were found in the ROM, best first
+func printPaletteMatches(matches []gbgfx.PaletteMatch, palettes []gbgfx.CGBPalette) {
+	if len(matches) == 0 {
+		fmt.Println("Palettes not found in the ROM")
+		return
+	}
+
+	for i, match := range matches {
+		if i == maxPaletteMatches {
+			fmt.Printf("... and %d more\n", len(matches)-maxPaletteMatches)
+			break
+		}
+
+		var words []string
+		for j, word := range palettes[match.Palette].RGB555() {
+			if palettes[match.Palette].Known[j] {
+				words = append(words, fmt.Sprintf("$%04X", word))
+			} else {
+				words = append(words, "-----")
+			}
+		}
+
+		fmt.Printf("BG palette %d (%s) found at location 0x%X (%s), distance %d\n",
+			match.Palette, strings.Join(words, " "), match.Offset, gbgfx.BankAddress(match.Offset), match.Distance)
+	}
+}
+
+// printOccurrences prints every ROM occurrence of every screen tile, best first
+func printOccurrences(groups []gbgfx.Occurrences) {
+	for _, group := range groups {
+		fmt.Printf("Tile '% X' found %d time(s):\n", group.ScreenTile, len(group.Matches))
+
+		for i, match := range group.Matches {
+			fmt.Printf("  %s (score %d, %s, BGP $%02X)\n", match.Address(), group.Scores[i], match.Flip, match.BGP)
+		}
+	}
 }
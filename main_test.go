@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithDefaultCommand(t *testing.T) {
+	tests := []struct {
+		arguments []string
+		want      []string
+	}{
+		{nil, nil},
+		{[]string{"--help"}, []string{"--help"}},
+		{[]string{"--version"}, []string{"--version"}},
+		{[]string{"--cache-dir", "DIR"}, []string{"--cache-dir", "DIR"}},
+		{[]string{"cache"}, []string{"cache"}},
+		{[]string{"--cache-dir", "DIR", "cache"}, []string{"--cache-dir", "DIR", "cache"}},
+		{[]string{"--cache-dir=DIR", "dump", "game.gb"}, []string{"--cache-dir=DIR", "dump", "game.gb"}},
+		{[]string{"--img", "screen.png", "game.gb"}, []string{"extract", "--img", "screen.png", "game.gb"}},
+		{[]string{"--cache-dir", "DIR", "--img", "screen.png", "game.gb"}, []string{"--cache-dir", "DIR", "extract", "--img", "screen.png", "game.gb"}},
+		{[]string{"--img", "screen.png", "game.gb", "--help"}, []string{"extract", "--img", "screen.png", "game.gb", "--help"}},
+	}
+
+	for _, test := range tests {
+		if got := withDefaultCommand(test.arguments); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: %q, want %q", test.arguments, got, test.want)
+		}
+	}
+}
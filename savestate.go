@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drpaneas/gbgraphics/gbgfx"
+)
+
+// loadSaveState rebuilds the screen out of a save state. The alignment is read
+// from the registers, so it's searched instead of guessed, unless it was given.
+// The screen of a DMG holds the colour numbers of the tiles, so it's searched with
+// the identity BGP, and the screen of a CGB is searched in CGB mode.
+func loadSaveState(userInput *extractArgs) (*gbgfx.SaveState, *gbgfx.Screenshot, error) {
+	state, err := gbgfx.ReadSaveState(userInput.State)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fmt.Printf("Save state: model %s, LCDC $%02X, SCX %d, SCY %d, WX %d, WY %d, BGP $%02X, OBP0 $%02X, OBP1 $%02X\n",
+		strings.TrimSpace(state.Model), state.LCDC, state.SCX, state.SCY, state.WX, state.WY, state.BGP, state.OBP0, state.OBP1)
+
+	screenshot, err := gbgfx.NewScreenshot(state.Screen())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	align := state.Alignment()
+	if window := state.Window(); !window.Empty() && (window.Min.X%8 != align.X || window.Min.Y%8 != align.Y) {
+		fmt.Printf("Warning: the window covers the screen from (%d,%d) on another tile grid than the background, its tiles may not be found\n",
+			window.Min.X, window.Min.Y)
+	}
+
+	if userInput.Align == "auto" {
+		userInput.Align = fmt.Sprintf("%d,%d", align.X, align.Y)
+	}
+
+	// The screen of a DMG is drawn in its colour numbers, BGP is only reported
+	if state.CGB() {
+		userInput.CGB = true
+	} else {
+		if userInput.BGP != "auto" {
+			fmt.Println("Warning: --bgp is ignored, the screen of the save state is drawn in the colour numbers of the tiles")
+		}
+
+		userInput.BGP = fmt.Sprintf("%02X", gbgfx.IdentityBGP)
+	}
+
+	return state, screenshot, nil
+}
+
+// printSpriteMatches searches the ROM for the tiles of the sprites on the screen of the save state
+func printSpriteMatches(rom *gbgfx.ROM, state *gbgfx.SaveState) {
+	tiles := state.SpriteTiles()
+	if len(tiles) == 0 {
+		return
+	}
+
+	tileOf := make(map[gbgfx.Tile]gbgfx.VRAMTile)
+	for _, tile := range tiles {
+		tileOf[tile.Tile] = tile
+	}
+
+	matches := rom.FindVRAM(tiles, false)
+	for _, match := range matches {
+		fmt.Printf("Sprite tile %s found at location %s, %s\n", tileOf[match.ScreenTile], match.Address(), match.Flip)
+	}
+
+	fmt.Printf("%d of %d sprite tile(s) found in the ROM\n", countFound(tiles, matches), len(tiles))
+}
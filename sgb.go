@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+
+	"github.com/drpaneas/gbgraphics/gbgfx"
+)
+
+// saveBorder searches the ROM for the tiles of the Super Gameboy border, and saves the
+// ones found on a sheet, in 16 greys, with a JSON index like the one of extract
+func saveBorder(path string, rom *gbgfx.ROM, screenshot *gbgfx.Screenshot, opts gbgfx.SheetOptions) error {
+	if screenshot.Border == nil {
+		fmt.Println("Warning: --sgb-border needs a 256x224 Super Gameboy screenshot")
+		return nil
+	}
+
+	matches := rom.FindSGBBorder(screenshot)
+	if len(matches) == 0 {
+		fmt.Println("Border tiles not found in the ROM, the game may compress them")
+		return nil
+	}
+
+	var tiles []image.Image
+
+	doc := sheetJSON{
+		Image:      path,
+		Format:     "4bpp",
+		Columns:    opts.Columns,
+		Spacing:    opts.Spacing,
+		Borders:    opts.Borders,
+		TileWidth:  8,
+		TileHeight: 8,
+	}
+
+	// Matches come in ROM order, the sheet has every tile once, as it is in the ROM
+	for _, match := range matches {
+		fmt.Printf("Border tile (%d,%d) found at location 0x%X (%s), %s\n", match.X, match.Y, match.Offset, gbgfx.BankAddress(match.Offset), match.Flip)
+
+		if n := len(doc.Tiles); n > 0 && doc.Tiles[n-1].Offset == fmt.Sprintf("0x%X", match.Offset) {
+			continue
+		}
+
+		tiles = append(tiles, gbgfx.Tile4BPPImage(rom.Data[match.Offset:match.Offset+gbgfx.SGBTileSize]))
+
+		i := len(doc.Tiles)
+		pos := opts.TilePosition(i, doc.TileWidth, doc.TileHeight)
+		doc.Tiles = append(doc.Tiles, sheetTileJSON{
+			Index:       i,
+			X:           pos.X,
+			Y:           pos.Y,
+			Offset:      fmt.Sprintf("0x%X", match.Offset),
+			BankAddress: gbgfx.BankAddress(match.Offset),
+			Found:       true,
+		})
+	}
+
+	sheet, err := gbgfx.TileSheet(tiles, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := gbgfx.SavePNG(path, sheet); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	indexPath := sheetIndexPath(path)
+	if err := os.WriteFile(indexPath, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+
+	fmt.Printf("%d border tile(s) found, %d different ones saved to '%s', index saved to '%s'\n", len(matches), len(tiles), path, indexPath)
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"strings"
+
+	"github.com/drpaneas/gbgraphics/gbgfx"
+)
+
+type sheetJSON struct {
+	Image      string          `json:"image"`
+	Format     string          `json:"format,omitempty"` // 4bpp for Super Gameboy border tiles, 2bpp otherwise
+	Columns    int             `json:"columns"`
+	Spacing    int             `json:"spacing"`
+	Borders    bool            `json:"borders"`
+	TileWidth  int             `json:"tile_width"`
+	TileHeight int             `json:"tile_height"`
+	Tiles      []sheetTileJSON `json:"tiles"`
+}
+
+type sheetTileJSON struct {
+	Index       int    `json:"index"`
+	X           int    `json:"x"` // top left pixel of the tile on the sheet
+	Y           int    `json:"y"`
+	Offset      string `json:"offset"`
+	BankAddress string `json:"bank_address"`
+	Found       bool   `json:"found"`  // the tile is on the screenshot, or in the VRAM dump
+	FlipX       bool   `json:"flip_x"` // how the tile was displayed on the screenshot
+	FlipY       bool   `json:"flip_y"`
+	BGP         string `json:"bgp,omitempty"`
+}
+
+// sheetIndexPath returns the path of the JSON index of a sheet: out.png -> out.json
+func sheetIndexPath(sheetPath string) string {
+	return strings.TrimSuffix(sheetPath, ".png") + ".json"
+}
+
+// saveSheet puts the tiles at the offsets on a single PNG, in the given order, and saves the location
+// of every tile in the ROM next to it as JSON. matches tells which of them were found, and source
+// where they were searched from.
+func saveSheet(path string, offsets []int, matches map[int]gbgfx.Match, source func(gbgfx.Match) string, opts gbgfx.SheetOptions, romBytes []byte) error {
+	if len(offsets) == 0 {
+		fmt.Println("No tiles found, no sheet saved")
+		return nil
+	}
+
+	var tiles []image.Image
+
+	for _, offset := range offsets {
+		if offset < 0 || offset+gbgfx.TileSize > len(romBytes) {
+			return fmt.Errorf("invalid start offset 0x%X", offset)
+		}
+
+		tiles = append(tiles, gbgfx.TileImage(romBytes[offset:offset+gbgfx.TileSize]))
+	}
+
+	sheet, err := gbgfx.TileSheet(tiles, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := gbgfx.SavePNG(path, sheet); err != nil {
+		return err
+	}
+
+	doc := sheetJSON{
+		Image:      path,
+		Columns:    opts.Columns,
+		Spacing:    opts.Spacing,
+		Borders:    opts.Borders,
+		TileWidth:  tiles[0].Bounds().Dx(),
+		TileHeight: tiles[0].Bounds().Dy(),
+	}
+
+	for i, offset := range offsets {
+		pos := opts.TilePosition(i, doc.TileWidth, doc.TileHeight)
+
+		tile := sheetTileJSON{
+			Index:       i,
+			X:           pos.X,
+			Y:           pos.Y,
+			Offset:      fmt.Sprintf("0x%X", offset),
+			BankAddress: gbgfx.BankAddress(offset),
+		}
+
+		match, found := matches[offset]
+		if found {
+			tile.Found = true
+			tile.FlipX = match.Flip == gbgfx.FlipX || match.Flip == gbgfx.FlipXY
+			tile.FlipY = match.Flip == gbgfx.FlipY || match.Flip == gbgfx.FlipXY
+			tile.BGP = fmt.Sprintf("$%02X", match.BGP)
+
+			fmt.Printf("'% X' (Found at location %s, %s, %s, BGP $%02X) placed as tile %d of '%s'\n",
+				romBytes[offset:offset+gbgfx.TileSize], match.Address(), source(match), match.Flip, match.BGP, i, path)
+		}
+
+		doc.Tiles = append(doc.Tiles, tile)
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	indexPath := sheetIndexPath(path)
+	if err := os.WriteFile(indexPath, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Sheet of %d tile(s) saved to '%s', index saved to '%s'\n", len(offsets), path, indexPath)
+
+	return nil
+}
+
+// saveBlocks expands the matches into the blocks of tile data around them
+// and saves all the blocks, in ROM order, on a single sheet
+func saveBlocks(path string, rom *gbgfx.ROM, matches []gbgfx.Match, limit int, matchAt map[int]gbgfx.Match, source func(gbgfx.Match) string, opts gbgfx.SheetOptions) error {
+	if limit < 0 {
+		return fmt.Errorf("invalid block limit %d", limit)
+	}
+
+	var offsets []int
+
+	for _, block := range rom.ExpandMatches(matches, gbgfx.BlockOptions{MaxTiles: limit}) {
+		fmt.Printf("Block 0x%X-0x%X (%s-%s): %d tile(s), %d of them found\n", block.Start, block.End-1,
+			gbgfx.BankAddress(block.Start), gbgfx.BankAddress(block.End-1), block.Tiles(), block.Hits)
+
+		offsets = append(offsets, block.Offsets()...)
+	}
+
+	return saveSheet(path, offsets, matchAt, source, opts, rom.Data)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/drpaneas/gbgraphics/gbgfx"
+)
+
+// Attribute bits of a .tilemap entry, the same as the CGB BG map attributes
+const (
+	attrPalette = 0x07
+	attrFlipX   = 1 << 5
+	attrFlipY   = 1 << 6
+)
+
+// notFound is the tile number of the positions whose tile isn't in the ROM
+const notFound = 0xFF
+
+type tilemapJSON struct {
+	Width   int                `json:"width"`
+	Height  int                `json:"height"`
+	AlignX  int                `json:"align_x"`
+	AlignY  int                `json:"align_y"`
+	Entries []tilemapEntryJSON `json:"entries"`
+}
+
+type tilemapEntryJSON struct {
+	X           int    `json:"x"`
+	Y           int    `json:"y"`
+	Tile        *int   `json:"tile"` // null if the tile isn't in the ROM
+	Offset      string `json:"offset,omitempty"`
+	BankAddress string `json:"bank_address,omitempty"`
+	FlipX       bool   `json:"flip_x"`
+	FlipY       bool   `json:"flip_y"`
+	Palette     *int   `json:"palette,omitempty"` // CGB background palette
+}
+
+// saveTilemap saves the tilemap in three formats:
+//   - prefix.tilemap is binary, two bytes per position, row by row: the number of
+//     the extracted tile (0xFF if not found) and the attributes (bits 0-2 = CGB palette, bit 5 = X flip, bit 6 = Y flip)
+//   - prefix.json and prefix.csv have the number, the ROM location and the flips of every position
+//
+// tileNumber maps the ROM offset of every extracted tile to its number (out_<number>.png).
+// paletteNumbers has the CGB palette of every position, and is nil for DMG screenshots.
+func saveTilemap(prefix string, tilemap *gbgfx.Tilemap, tileNumber map[int]int, paletteNumbers []int) error {
+	if len(tileNumber) > notFound {
+		return fmt.Errorf("too many tiles (%d) for a tilemap with 1-byte tile numbers", len(tileNumber))
+	}
+
+	var binary []byte
+
+	doc := tilemapJSON{
+		Width:  tilemap.Width,
+		Height: tilemap.Height,
+		AlignX: tilemap.Align.X,
+		AlignY: tilemap.Align.Y,
+	}
+
+	records := [][]string{{"x", "y", "tile", "offset", "bank_address", "flip_x", "flip_y", "palette"}}
+
+	for j, entry := range tilemap.Entries {
+		number, ok := tileNumber[entry.Match.Offset]
+		if !entry.Found || !ok {
+			binary = append(binary, notFound, 0)
+			doc.Entries = append(doc.Entries, tilemapEntryJSON{X: entry.X, Y: entry.Y})
+			records = append(records, []string{strconv.Itoa(entry.X), strconv.Itoa(entry.Y), "", "", "", "", "", ""})
+
+			continue
+		}
+
+		flipX := entry.Flip == gbgfx.FlipX || entry.Flip == gbgfx.FlipXY
+		flipY := entry.Flip == gbgfx.FlipY || entry.Flip == gbgfx.FlipXY
+
+		var attributes byte
+		if flipX {
+			attributes |= attrFlipX
+		}
+
+		if flipY {
+			attributes |= attrFlipY
+		}
+
+		var palette *int
+		paletteField := ""
+
+		if paletteNumbers != nil {
+			palette = &paletteNumbers[j]
+			paletteField = strconv.Itoa(*palette)
+			attributes |= byte(*palette) & attrPalette
+		}
+
+		offset := fmt.Sprintf("0x%X", entry.Match.Offset)
+		bankAddress := gbgfx.BankAddress(entry.Match.Offset)
+
+		binary = append(binary, byte(number), attributes)
+		doc.Entries = append(doc.Entries, tilemapEntryJSON{
+			X:           entry.X,
+			Y:           entry.Y,
+			Tile:        &number,
+			Offset:      offset,
+			BankAddress: bankAddress,
+			FlipX:       flipX,
+			FlipY:       flipY,
+			Palette:     palette,
+		})
+		records = append(records, []string{
+			strconv.Itoa(entry.X), strconv.Itoa(entry.Y), strconv.Itoa(number), offset, bankAddress,
+			strconv.FormatBool(flipX), strconv.FormatBool(flipY), paletteField,
+		})
+	}
+
+	if err := os.WriteFile(prefix+".tilemap", binary, 0o644); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(prefix+".json", append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+
+	f, err := os.Create(prefix + ".csv")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(records); err != nil {
+		return err
+	}
+
+	fmt.Printf("Tilemap (%dx%d) saved to '%s.tilemap', '%s.json' and '%s.csv'\n", tilemap.Width, tilemap.Height, prefix, prefix, prefix)
+
+	return nil
+}
+
+// printMapMatches prints where the tile numbers of the tilemap were found in the ROM
+func printMapMatches(matches []gbgfx.MapMatch) {
+	if len(matches) == 0 {
+		fmt.Println("Tilemap not found in the ROM")
+		return
+	}
+
+	for _, match := range matches {
+		rows := fmt.Sprintf("row %d", match.FirstRow)
+		if match.Rows > 1 {
+			rows = fmt.Sprintf("rows %d-%d, %d bytes per row", match.FirstRow, match.FirstRow+match.Rows-1, match.Stride)
+		}
+
+		var modes []string
+		if match.Mode8000 {
+			modes = append(modes, fmt.Sprintf("$8000 addressing: VRAM $%04X", match.VRAM8000()))
+		}
+
+		if match.Mode8800 {
+			modes = append(modes, fmt.Sprintf("$8800 addressing: VRAM $%04X", match.VRAM8800()))
+		}
+
+		fmt.Printf("Tilemap %s found at 0x%X (%s), tile at 0x%X is number $%02X (%s)\n",
+			rows, match.Offset, gbgfx.BankAddress(match.Offset), match.RefOffset, match.Base, strings.Join(modes, ", "))
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/drpaneas/gbgraphics/gbgfx"
+)
+
+// extractVRAM searches the ROM for every tile of a VRAM dump and saves them,
+// the same way extract does for the tiles of a screenshot
+func extractVRAM(userInput *extractArgs, rom *gbgfx.ROM, cacheDir string) {
+	vram, err := gbgfx.ReadVRAMDump(userInput.VRAM)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if userInput.Tilemap != "" || userInput.FindMap || userInput.CGB || userInput.SGBBorder != "" {
+		fmt.Println("Warning: --tilemap, --find-map, --cgb and --sgb-border need a screenshot, they are ignored with --vram")
+	}
+
+	extractVRAMTiles(userInput, rom, cacheDir, vram)
+}
+
+// extractVRAMTiles searches the ROM for the tiles of the video RAM and saves the ones found
+func extractVRAMTiles(userInput *extractArgs, rom *gbgfx.ROM, cacheDir string, vram *gbgfx.VRAM) {
+	tiles := vram.Tiles()
+	fmt.Printf("VRAM: %d bank(s), %d different tile(s) that aren't blank\n", len(vram.Banks), len(tiles))
+
+	if err := rom.LoadIndex(cacheDir); err != nil {
+		fmt.Println("Warning:", err)
+	}
+
+	// A tile may be in VRAM more than once, the first one names it
+	tileOf := make(map[gbgfx.Tile]gbgfx.VRAMTile)
+	for _, tile := range tiles {
+		tileOf[tile.Tile] = tile
+	}
+
+	matches := rom.FindVRAM(tiles, userInput.All)
+
+	var uniqueMatches []gbgfx.Match
+	found := make(map[int]bool)
+
+	for _, match := range matches {
+		if !found[match.Offset] {
+			found[match.Offset] = true
+			uniqueMatches = append(uniqueMatches, match)
+		}
+	}
+
+	if userInput.All {
+		uniqueMatches, _ = bestOccurrences(uniqueMatches)
+	}
+
+	fmt.Printf("%d of %d VRAM tile(s) found in the ROM\n", countFound(tiles, uniqueMatches), len(tiles))
+
+	saveMatches(userInput, rom, uniqueMatches, func(match gbgfx.Match) string {
+		return tileOf[match.ScreenTile].String()
+	})
+}
+
+// countFound counts the VRAM tiles that have a match
+func countFound(tiles []gbgfx.VRAMTile, matches []gbgfx.Match) int {
+	matched := make(map[gbgfx.Tile]bool)
+	for _, match := range matches {
+		matched[match.ScreenTile] = true
+	}
+
+	n := 0
+	for _, tile := range tiles {
+		if matched[tile.Tile] {
+			n++
+		}
+	}
+
+	return n
+}